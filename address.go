@@ -18,15 +18,15 @@ type Addresses struct {
 
 // Address represents an address from the Onfido API
 type Address struct {
-	FlatNumber     string `json:"flat_number"`
-	BuildingNumber string `json:"building_number"`
-	BuildingName   string `json:"building_name"`
-	Street         string `json:"street"`
-	SubStreet      string `json:"sub_street"`
-	Town           string `json:"town"`
-	State          string `json:"state"`
-	Postcode       string `json:"postcode"`
-	Country        string `json:"country"`
+	FlatNumber     string      `json:"flat_number"`
+	BuildingNumber string      `json:"building_number"`
+	BuildingName   string      `json:"building_name"`
+	Street         string      `json:"street"`
+	SubStreet      string      `json:"sub_street"`
+	Town           string      `json:"town"`
+	State          string      `json:"state"`
+	Postcode       string      `json:"postcode"`
+	Country        CountryCode `json:"country"`
 
 	// Applicant specific
 	StartDate string `json:"start_date,omitempty"`
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"net/url"
 	"time"
 )
 
@@ -18,15 +19,61 @@ const (
 	IDNumberTypeTaxID           IDNumberType = "tax_id"
 	IDNumberTypeIdentityCard    IDNumberType = "identity_card"
 	IDNumberTypeDrivingLicense  IDNumberType = "driving_licence"
+	IDNumberTypeShareCode       IDNumberType = "share_code"
+	IDNumberTypeVoterID         IDNumberType = "voter_id"
+	IDNumberTypePassport        IDNumberType = "passport"
+	IDNumberTypeOther           IDNumberType = "other"
 )
 
 // IDNumber represents an ID number from the Onfido API
 type IDNumber struct {
 	Type      IDNumberType `json:"type,omitempty"`
 	Value     string       `json:"value,omitempty"`
-	StateCode string       `json:"state_code,omitempty"`
+	StateCode USStateCode  `json:"state_code,omitempty"`
 }
 
+// ConsentName represents a consent an applicant can give (see `ConsentName*` constants for possible values)
+type ConsentName string
+
+// Supported consents
+const (
+	ConsentNamePrivacyNoticesRead      ConsentName = "privacy_notices_read"
+	ConsentNameSSNVerification         ConsentName = "ssn_verification"
+	ConsentNamePhoneNumberVerification ConsentName = "phone_number_verification"
+)
+
+// Consent represents whether an applicant has given a consent
+type Consent struct {
+	Name    ConsentName `json:"name"`
+	Granted bool        `json:"granted"`
+}
+
+// Consents represents the consents given by an applicant
+type Consents []Consent
+
+// Granted reports whether the consent with the given name has been granted.
+func (cs Consents) Granted(name ConsentName) bool {
+	for _, c := range cs {
+		if c.Name == name {
+			return c.Granted
+		}
+	}
+	return false
+}
+
+// Location represents where an applicant is located.
+// It is required for applicants in the US.
+type Location struct {
+	IPAddress          string      `json:"ip_address,omitempty"`
+	CountryOfResidence CountryCode `json:"country_of_residence,omitempty"`
+}
+
+// Applicant errors
+var (
+	ErrInvalidApplicantID  = errors.New("invalid applicant id")
+	ErrEmptyApplicantPatch = errors.New("applicant patch has no fields set")
+)
+
 // Applicants represents a list of applicants from the Onfido API
 type Applicants struct {
 	Applicants []*Applicant `json:"applicants"`
@@ -34,22 +81,61 @@ type Applicants struct {
 
 // Applicant represents an applicant from the Onfido API
 type Applicant struct {
-	ID         string     `json:"id,omitempty"`
-	CreatedAt  *time.Time `json:"created_at,omitempty"`
-	Sandbox    bool       `json:"sandbox,omitempty"`
-	Title      string     `json:"title,omitempty"`
-	FirstName  string     `json:"first_name,omitempty"`
-	LastName   string     `json:"last_name,omitempty"`
-	MiddleName string     `json:"middle_name,omitempty"`
-	Email      string     `json:"email,omitempty"`
-	DOB        string     `json:"dob,omitempty"`
-	IDNumbers  []IDNumber `json:"id_numbers,omitempty"`
-	Address    Address    `json:"address,omitempty"`
+	ID          string     `json:"id,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	Sandbox     bool       `json:"sandbox,omitempty"`
+	Title       string     `json:"title,omitempty"`
+	FirstName   string     `json:"first_name,omitempty"`
+	LastName    string     `json:"last_name,omitempty"`
+	MiddleName  string     `json:"middle_name,omitempty"`
+	Email       string     `json:"email,omitempty"`
+	PhoneNumber string     `json:"phone_number,omitempty"`
+	DOB         string     `json:"dob,omitempty"`
+	IDNumbers   []IDNumber `json:"id_numbers,omitempty"`
+	Address     Address    `json:"address,omitempty"`
+	// Addresses is the applicant's address history, for accounts whose
+	// reports take previous addresses into account.
+	Addresses []Address `json:"addresses,omitempty"`
+	Location  *Location `json:"location,omitempty"`
+	Consents  Consents  `json:"consents,omitempty"`
+	// DeleteAt is when a deleted applicant will be permanently removed,
+	// until then it can be restored with RestoreApplicant.
+	DeleteAt *time.Time `json:"delete_at,omitempty"`
+}
+
+// MarshalJSON encodes the applicant, omitting the address if it is empty
+// so applicants without an address can be created and updated.
+func (a Applicant) MarshalJSON() ([]byte, error) {
+	type applicant Applicant
+	v := struct {
+		applicant
+		Address *Address `json:"address,omitempty"`
+	}{applicant: applicant(a)}
+	if a.Address != (Address{}) {
+		v.Address = &a.Address
+	}
+	return json.Marshal(v)
+}
+
+// ScheduledForDeletion reports whether the applicant has been deleted and
+// is waiting to be permanently removed.
+func (a *Applicant) ScheduledForDeletion() bool {
+	return a.DeleteAt != nil && !a.DeleteAt.IsZero()
+}
+
+// ListApplicantsOptions represents the options for listing applicants
+type ListApplicantsOptions struct {
+	// IncludeDeleted includes applicants scheduled for deletion.
+	IncludeDeleted bool
 }
 
 // CreateApplicant creates a new applicant.
 // see https://documentation.onfido.com/?shell#create-applicant
 func (c *client) CreateApplicant(ctx context.Context, a Applicant) (*Applicant, error) {
+	if err := a.validateCodes(); err != nil {
+		return nil, err
+	}
+
 	jsonStr, err := json.Marshal(a)
 	if err != nil {
 		return nil, err
@@ -65,6 +151,31 @@ func (c *client) CreateApplicant(ctx context.Context, a Applicant) (*Applicant,
 	return &resp, err
 }
 
+// validateCodes checks the applicant's country and state codes are known,
+// so that mistakes such as alpha-2 codes are caught before new applicants
+// are sent to Onfido.
+func (a Applicant) validateCodes() error {
+	if err := a.Address.Country.Validate(); err != nil {
+		return err
+	}
+	for _, addr := range a.Addresses {
+		if err := addr.Country.Validate(); err != nil {
+			return err
+		}
+	}
+	if a.Location != nil {
+		if err := a.Location.CountryOfResidence.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, n := range a.IDNumbers {
+		if err := n.StateCode.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // DeleteApplicant deletes an applicant by its id.
 // see https://documentation.onfido.com/?shell#delete-applicant
 func (c *client) DeleteApplicant(ctx context.Context, id string) error {
@@ -76,6 +187,17 @@ func (c *client) DeleteApplicant(ctx context.Context, id string) error {
 	return err
 }
 
+// RestoreApplicant restores an applicant scheduled for deletion by its id.
+// see https://documentation.onfido.com/?shell#restore-applicant
+func (c *client) RestoreApplicant(ctx context.Context, id string) error {
+	req, err := c.newRequest("POST", "/applicants/"+id+"/restore", nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(ctx, req, nil)
+	return err
+}
+
 // GetApplicant retrieves an applicant by its id.
 // see https://documentation.onfido.com/?shell#retrieve-applicant
 func (c *client) GetApplicant(ctx context.Context, id string) (*Applicant, error) {
@@ -102,6 +224,12 @@ func (i *ApplicantIter) Applicant() *Applicant {
 // ListApplicants retrieves the list of applicants.
 // see https://documentation.onfido.com/?shell#list-applicants
 func (c *client) ListApplicants() *ApplicantIter {
+	return c.ListApplicantsWithOptions(ListApplicantsOptions{})
+}
+
+// ListApplicantsWithOptions retrieves the list of applicants using the given options.
+// see https://documentation.onfido.com/?shell#list-applicants
+func (c *client) ListApplicantsWithOptions(opts ListApplicantsOptions) *ApplicantIter {
 	handler := func(body []byte) ([]interface{}, error) {
 		var a Applicants
 		if err := json.Unmarshal(body, &a); err != nil {
@@ -115,9 +243,16 @@ func (c *client) ListApplicants() *ApplicantIter {
 		return values, nil
 	}
 
+	nextURL := "/applicants"
+	if opts.IncludeDeleted {
+		q := url.Values{}
+		q.Set("include_deleted", "true")
+		nextURL += "?" + q.Encode()
+	}
+
 	return &ApplicantIter{&iter{
 		c:       c,
-		nextURL: "/applicants",
+		nextURL: nextURL,
 		handler: handler,
 	}}
 }
@@ -126,7 +261,7 @@ func (c *client) ListApplicants() *ApplicantIter {
 // see https://documentation.onfido.com/?shell#update-applicant
 func (c *client) UpdateApplicant(ctx context.Context, a Applicant) (*Applicant, error) {
 	if a.ID == "" {
-		return nil, errors.New("invalid applicant id")
+		return nil, ErrInvalidApplicantID
 	}
 	jsonStr, err := json.Marshal(a)
 	if err != nil {
@@ -142,3 +277,94 @@ func (c *client) UpdateApplicant(ctx context.Context, a Applicant) (*Applicant,
 	_, err = c.do(ctx, req, &resp)
 	return &resp, err
 }
+
+// ApplicantPatch represents a partial update of an applicant. Only the
+// fields which are set are sent, nil fields are left unchanged. Set a
+// string field to a pointer to "" to clear it.
+type ApplicantPatch struct {
+	Title       *string
+	FirstName   *string
+	LastName    *string
+	MiddleName  *string
+	Email       *string
+	PhoneNumber *string
+	DOB         *string
+	IDNumbers   []IDNumber
+	Address     *Address
+	// Addresses is the applicant's address history, set it to an empty
+	// slice to clear it.
+	Addresses []Address
+	Location  *Location
+	Consents  Consents
+}
+
+// StringPtr returns a pointer to s, for setting ApplicantPatch fields.
+func StringPtr(s string) *string {
+	return &s
+}
+
+// MarshalJSON encodes only the fields which are set.
+func (p ApplicantPatch) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]interface{})
+	for name, v := range map[string]*string{
+		"title":        p.Title,
+		"first_name":   p.FirstName,
+		"last_name":    p.LastName,
+		"middle_name":  p.MiddleName,
+		"email":        p.Email,
+		"phone_number": p.PhoneNumber,
+		"dob":          p.DOB,
+	} {
+		if v != nil {
+			fields[name] = *v
+		}
+	}
+	if p.IDNumbers != nil {
+		fields["id_numbers"] = p.IDNumbers
+	}
+	if p.Address != nil {
+		fields["address"] = p.Address
+	}
+	if p.Addresses != nil {
+		fields["addresses"] = p.Addresses
+	}
+	if p.Location != nil {
+		fields["location"] = p.Location
+	}
+	if p.Consents != nil {
+		fields["consents"] = p.Consents
+	}
+	return json.Marshal(fields)
+}
+
+// IsEmpty reports whether no fields are set.
+func (p ApplicantPatch) IsEmpty() bool {
+	return p.Title == nil && p.FirstName == nil && p.LastName == nil &&
+		p.MiddleName == nil && p.Email == nil && p.PhoneNumber == nil &&
+		p.DOB == nil && p.IDNumbers == nil && p.Address == nil &&
+		p.Addresses == nil && p.Location == nil && p.Consents == nil
+}
+
+// UpdateApplicantFields updates only the fields of an applicant set in the patch.
+// see https://documentation.onfido.com/?shell#update-applicant
+func (c *client) UpdateApplicantFields(ctx context.Context, id string, p ApplicantPatch) (*Applicant, error) {
+	if id == "" {
+		return nil, ErrInvalidApplicantID
+	}
+	if p.IsEmpty() {
+		return nil, ErrEmptyApplicantPatch
+	}
+	jsonStr, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.newRequest("PUT", "/applicants/"+id, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp Applicant
+	_, err = c.do(ctx, req, &resp)
+	return &resp, err
+}
@@ -0,0 +1,513 @@
+package onfido
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BulkFormat represents the format of a bulk import source
+type BulkFormat string
+
+// Supported bulk import formats
+const (
+	// BulkFormatCSV is CSV with a header row naming each column's field.
+	BulkFormatCSV BulkFormat = "csv"
+	// BulkFormatJSONL is one applicant JSON object per line.
+	BulkFormatJSONL BulkFormat = "jsonl"
+)
+
+// Bulk import errors
+var (
+	ErrUnknownBulkFormat = errors.New("unknown bulk import format")
+	ErrUnknownBulkColumn = errors.New("unknown bulk import column")
+)
+
+// Bulk row error types, API errors use the type of the Onfido error.
+const (
+	BulkErrorParse            = "parse_error"
+	BulkErrorValidation       = "validation_error"
+	BulkErrorDuplicateKey     = "duplicate_key"
+	BulkErrorMissingReference = "missing_reference"
+	BulkErrorRequest          = "request_error"
+)
+
+// defaultBulkConcurrency is how many applicants BulkCreateApplicants
+// creates at once if BulkCreateOptions.Concurrency isn't set.
+const defaultBulkConcurrency = 4
+
+// bulkReferenceField is the field holding a row's external reference.
+const bulkReferenceField = "reference"
+
+// bulkFields maps the field names of a bulk import to the applicant
+// fields they set.
+var bulkFields = map[string]func(a *Applicant, v string) error{
+	"title":        func(a *Applicant, v string) error { a.Title = v; return nil },
+	"first_name":   func(a *Applicant, v string) error { a.FirstName = v; return nil },
+	"middle_name":  func(a *Applicant, v string) error { a.MiddleName = v; return nil },
+	"last_name":    func(a *Applicant, v string) error { a.LastName = v; return nil },
+	"email":        func(a *Applicant, v string) error { a.Email = v; return nil },
+	"phone_number": func(a *Applicant, v string) error { a.PhoneNumber = v; return nil },
+	"dob":          func(a *Applicant, v string) error { a.DOB = v; return nil },
+
+	"address.flat_number":     func(a *Applicant, v string) error { a.Address.FlatNumber = v; return nil },
+	"address.building_number": func(a *Applicant, v string) error { a.Address.BuildingNumber = v; return nil },
+	"address.building_name":   func(a *Applicant, v string) error { a.Address.BuildingName = v; return nil },
+	"address.street":          func(a *Applicant, v string) error { a.Address.Street = v; return nil },
+	"address.sub_street":      func(a *Applicant, v string) error { a.Address.SubStreet = v; return nil },
+	"address.town":            func(a *Applicant, v string) error { a.Address.Town = v; return nil },
+	"address.state":           func(a *Applicant, v string) error { a.Address.State = v; return nil },
+	"address.postcode":        func(a *Applicant, v string) error { a.Address.Postcode = v; return nil },
+	"address.country": func(a *Applicant, v string) (err error) {
+		a.Address.Country, err = ParseCountryCode(v)
+		return err
+	},
+
+	"location.ip_address": func(a *Applicant, v string) error {
+		bulkLocation(a).IPAddress = v
+		return nil
+	},
+	"location.country_of_residence": func(a *Applicant, v string) (err error) {
+		bulkLocation(a).CountryOfResidence, err = ParseCountryCode(v)
+		return err
+	},
+
+	"id_number.type": func(a *Applicant, v string) error {
+		bulkIDNumber(a).Type = IDNumberType(v)
+		return nil
+	},
+	"id_number.value": func(a *Applicant, v string) error {
+		bulkIDNumber(a).Value = v
+		return nil
+	},
+	"id_number.state_code": func(a *Applicant, v string) (err error) {
+		bulkIDNumber(a).StateCode, err = ParseUSStateCode(v)
+		return err
+	},
+}
+
+func bulkLocation(a *Applicant) *Location {
+	if a.Location == nil {
+		a.Location = &Location{}
+	}
+	return a.Location
+}
+
+func bulkIDNumber(a *Applicant) *IDNumber {
+	if len(a.IDNumbers) == 0 {
+		a.IDNumbers = []IDNumber{{}}
+	}
+	return &a.IDNumbers[0]
+}
+
+// BulkCreateOptions configures BulkCreateApplicants
+type BulkCreateOptions struct {
+	// Format is the format of the source. Required.
+	Format BulkFormat
+	// Columns maps CSV column names to field names, for sources whose
+	// header doesn't use the field names. Columns which are neither
+	// mapped nor field names are rejected.
+	Columns map[string]string
+	// Concurrency is how many applicants are created at once, 4 if not set.
+	Concurrency int
+	// RequestsPerSecond limits the rate applicants are created at, to
+	// stay within the account's API rate limit. Unlimited if not set.
+	RequestsPerSecond float64
+	// ValidateFor validates each row for the given reports before it is
+	// created, see ValidateApplicantForReports.
+	ValidateFor []ReportName
+	// Results, if set, receives a JSON line per row with the created
+	// applicant's ID or the row's error.
+	Results io.Writer
+	// Completed maps row keys to the IDs of applicants already created
+	// for them, as returned by ReadBulkResults. Those rows are skipped,
+	// so a failed import can be retried without creating duplicates.
+	// Rows without a reference are keyed by their position, so a retry
+	// must use the source unchanged: if rows were added, removed or
+	// reordered the keys refer to different rows. Set RequireReference
+	// to rule this out.
+	Completed map[string]string
+	// RequireReference fails rows without a reference, rather than keying
+	// them by their position.
+	RequireReference bool
+}
+
+// BulkRowError represents why a bulk import row failed
+type BulkRowError struct {
+	Type    string      `json:"type"`
+	Message string      `json:"message"`
+	Status  int         `json:"status,omitempty"`
+	Fields  ErrorFields `json:"fields,omitempty"`
+}
+
+func (e *BulkRowError) Error() string {
+	return e.Type + ": " + e.Message
+}
+
+// BulkCreateResult represents the result of a bulk import row. Key is the
+// row's reference, or "row:<n>" for rows without one. A positional key
+// only identifies the same row while the source is unchanged, see
+// BulkCreateOptions.Completed.
+type BulkCreateResult struct {
+	Row         int           `json:"row"`
+	Key         string        `json:"key"`
+	ApplicantID string        `json:"applicant_id,omitempty"`
+	Skipped     bool          `json:"skipped,omitempty"`
+	Error       *BulkRowError `json:"error,omitempty"`
+}
+
+// BulkCreateSummary counts the results of a bulk import
+type BulkCreateSummary struct {
+	Created int
+	Skipped int
+	Failed  int
+}
+
+// bulkRow is a row read from a bulk import source.
+type bulkRow struct {
+	row       int
+	reference string
+	applicant Applicant
+	err       *BulkRowError
+}
+
+func (r *bulkRow) key() string {
+	if r.reference != "" {
+		return r.reference
+	}
+	return "row:" + strconv.Itoa(r.row)
+}
+
+// ReadBulkResults reads a results file written by BulkCreateApplicants,
+// returning the row keys which were created or skipped mapped to their
+// applicant IDs, for BulkCreateOptions.Completed.
+func ReadBulkResults(r io.Reader) (map[string]string, error) {
+	completed := make(map[string]string)
+	dec := json.NewDecoder(r)
+	for {
+		var res BulkCreateResult
+		if err := dec.Decode(&res); err == io.EOF {
+			return completed, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if res.ApplicantID != "" {
+			completed[res.Key] = res.ApplicantID
+		}
+	}
+}
+
+// BulkCreateApplicants creates an applicant for each row of source,
+// Concurrency at a time. Rows which fail are reported in Results and
+// counted in the summary, they don't stop the import. An error is only
+// returned if the source can't be read or ctx is done.
+// see https://documentation.onfido.com/?shell#create-applicant
+func (c *client) BulkCreateApplicants(ctx context.Context, source io.Reader, opts BulkCreateOptions) (*BulkCreateSummary, error) {
+	var read func(ctx context.Context, rows chan<- *bulkRow) error
+	switch opts.Format {
+	case BulkFormatCSV:
+		cr := csv.NewReader(source)
+		header, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("reading csv header: %w", err)
+		}
+		fields, err := bulkColumns(header, opts.Columns)
+		if err != nil {
+			return nil, err
+		}
+		read = func(ctx context.Context, rows chan<- *bulkRow) error {
+			return readBulkCSV(ctx, cr, fields, rows)
+		}
+	case BulkFormatJSONL:
+		read = func(ctx context.Context, rows chan<- *bulkRow) error {
+			return readBulkJSONL(ctx, source, rows)
+		}
+	default:
+		return nil, ErrUnknownBulkFormat
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBulkConcurrency
+	}
+	limiter := newRateLimiter(opts.RequestsPerSecond)
+	defer limiter.stop()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		summary BulkCreateSummary
+		seen    = make(map[string]int)
+		enc     *json.Encoder
+		wErr    error
+	)
+	if opts.Results != nil {
+		enc = json.NewEncoder(opts.Results)
+	}
+	record := func(res BulkCreateResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case res.Error != nil:
+			summary.Failed++
+		case res.Skipped:
+			summary.Skipped++
+		default:
+			summary.Created++
+		}
+		if enc != nil && wErr == nil {
+			if wErr = enc.Encode(res); wErr != nil {
+				cancel()
+			}
+		}
+	}
+
+	rows := make(chan *bulkRow)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range rows {
+				if err := limiter.wait(ctx); err != nil {
+					return
+				}
+				res := BulkCreateResult{Row: r.row, Key: r.key()}
+				a, err := c.CreateApplicant(ctx, r.applicant)
+				switch {
+				case a != nil && a.ID != "":
+					// The applicant was created even if ctx is done by
+					// now, so it is recorded for a retry to skip it
+					res.ApplicantID = a.ID
+				case ctx.Err() != nil:
+					return
+				case err != nil:
+					res.Error = bulkRowError(err)
+				}
+				record(res)
+			}
+		}()
+	}
+
+	parsed := make(chan *bulkRow)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(parsed)
+		readErr <- read(ctx, parsed)
+	}()
+
+	for r := range parsed {
+		key := r.key()
+		res := BulkCreateResult{Row: r.row, Key: key}
+		if r.err == nil && opts.RequireReference && r.reference == "" {
+			r.err = &BulkRowError{Type: BulkErrorMissingReference, Message: "row has no reference"}
+		}
+		if r.err == nil {
+			if prev, ok := seen[key]; ok {
+				r.err = &BulkRowError{Type: BulkErrorDuplicateKey, Message: fmt.Sprintf("key is also used by row %d", prev)}
+			} else {
+				seen[key] = r.row
+			}
+		}
+		if r.err == nil && len(opts.ValidateFor) > 0 {
+			if errs := ValidateApplicantForReports(r.applicant, opts.ValidateFor); len(errs) > 0 {
+				r.err = bulkValidationError(errs)
+			}
+		}
+
+		if r.err != nil {
+			res.Error = r.err
+			record(res)
+			continue
+		}
+		if id, ok := opts.Completed[key]; ok {
+			res.ApplicantID, res.Skipped = id, true
+			record(res)
+			continue
+		}
+
+		select {
+		case rows <- r:
+		case <-ctx.Done():
+		}
+	}
+	close(rows)
+	wg.Wait()
+
+	if err := <-readErr; err != nil && ctx.Err() == nil {
+		return &summary, err
+	}
+	if wErr != nil {
+		return &summary, fmt.Errorf("writing results: %w", wErr)
+	}
+	return &summary, ctx.Err()
+}
+
+// bulkColumns maps a CSV header to field names.
+func bulkColumns(header []string, columns map[string]string) ([]string, error) {
+	fields := make([]string, len(header))
+	for i, col := range header {
+		col = strings.TrimSpace(col)
+		if f, ok := columns[col]; ok {
+			col = f
+		}
+		if _, ok := bulkFields[col]; !ok && col != bulkReferenceField {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownBulkColumn, header[i])
+		}
+		fields[i] = col
+	}
+	return fields, nil
+}
+
+func readBulkCSV(ctx context.Context, cr *csv.Reader, fields []string, rows chan<- *bulkRow) error {
+	cr.FieldsPerRecord = len(fields)
+	for n := 1; ; n++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		r := &bulkRow{row: n}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			r.err = &BulkRowError{Type: BulkErrorParse, Message: perr.Error()}
+		} else if err != nil {
+			return err
+		} else {
+			for i, v := range record {
+				v = strings.TrimSpace(v)
+				if v == "" {
+					continue
+				}
+				if fields[i] == bulkReferenceField {
+					r.reference = v
+				} else if err := bulkFields[fields[i]](&r.applicant, v); err != nil {
+					r.err = &BulkRowError{Type: BulkErrorParse, Message: fields[i] + ": " + err.Error()}
+					break
+				}
+			}
+		}
+
+		select {
+		case rows <- r:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func readBulkJSONL(ctx context.Context, source io.Reader, rows chan<- *bulkRow) error {
+	sc := bufio.NewScanner(source)
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var v struct {
+			Applicant
+			Reference string `json:"reference"`
+		}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+
+		r := &bulkRow{row: n}
+		if err := dec.Decode(&v); err != nil {
+			r.err = &BulkRowError{Type: BulkErrorParse, Message: err.Error()}
+		} else {
+			r.applicant, r.reference = v.Applicant, v.Reference
+		}
+
+		select {
+		case rows <- r:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return sc.Err()
+}
+
+// bulkRowError converts a CreateApplicant error to a row error.
+func bulkRowError(err error) *BulkRowError {
+	var oErr *Error
+	if !errors.As(err, &oErr) {
+		return &BulkRowError{Type: BulkErrorRequest, Message: err.Error()}
+	}
+	e := &BulkRowError{Type: oErr.Err.Type, Message: oErr.Error(), Fields: oErr.Err.Fields}
+	if e.Type == "" {
+		e.Type = BulkErrorRequest
+	}
+	if oErr.Resp != nil {
+		e.Status = oErr.Resp.StatusCode
+	}
+	return e
+}
+
+// bulkValidationError converts validation errors to a row error, with a
+// field's messages listed under its name like an Onfido validation error.
+func bulkValidationError(errs []FieldError) *BulkRowError {
+	e := &BulkRowError{Type: BulkErrorValidation, Fields: ErrorFields{}}
+	msgs := make([]string, len(errs))
+	for i, fe := range errs {
+		msgs[i] = fe.Error()
+		prev, _ := e.Fields[fe.Field].([]string)
+		e.Fields[fe.Field] = append(prev, fe.Message)
+	}
+	e.Message = strings.Join(msgs, "; ")
+	return e
+}
+
+// rateLimiter spaces out requests to a maximum rate, a nil limiter
+// doesn't limit.
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if !(perSecond > 0) {
+		return nil
+	}
+	// Clamp the interval to what a ticker can represent, rates too high
+	// for a nanosecond interval would otherwise make it panic
+	var interval time.Duration
+	switch d := float64(time.Second) / perSecond; {
+	case d < 1:
+		interval = time.Nanosecond
+	case d >= math.MaxInt64:
+		interval = math.MaxInt64
+	default:
+		interval = time.Duration(d)
+	}
+	return &rateLimiter{ticker: time.NewTicker(interval)}
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	select {
+	case <-l.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *rateLimiter) stop() {
+	if l != nil {
+		l.ticker.Stop()
+	}
+}
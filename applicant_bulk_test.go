@@ -0,0 +1,255 @@
+package onfido
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readResults(t *testing.T, buf *bytes.Buffer) map[int]BulkCreateResult {
+	results := make(map[int]BulkCreateResult)
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for dec.More() {
+		var res BulkCreateResult
+		if err := dec.Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		results[res.Row] = res
+	}
+	return results
+}
+
+func TestBulkCreateApplicants_CSV(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	source := strings.Join([]string{
+		"customer_id,first_name,last_name,email,address.street,address.town,address.postcode,address.country",
+		"c-1,Jane,Doe,jane@example.com,Main Street,London,SW4 6EH,GB",
+		"c-2,John,Invalid,,,,,",
+		"c-3,Ann,Smith,,,,,Atlantis",
+		"c-1,Bob,Brown,,,,,",
+		",Eve,Jones,,,,,",
+		"c-6,Too,Short",
+		"c-7,Max,Done,,,,,",
+	}, "\n")
+
+	var results bytes.Buffer
+	summary, err := client.BulkCreateApplicants(context.Background(), strings.NewReader(source), BulkCreateOptions{
+		Format:    BulkFormatCSV,
+		Columns:   map[string]string{"customer_id": "reference"},
+		Results:   &results,
+		Completed: map[string]string{"c-7": "app-existing"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &BulkCreateSummary{Created: 2, Skipped: 1, Failed: 4}, summary)
+
+	res := readResults(t, &results)
+	assert.Len(t, res, 7)
+	assert.Equal(t, BulkCreateResult{Row: 1, Key: "c-1", ApplicantID: srv.applicantID("Jane")}, res[1])
+	assert.Equal(t, BulkCreateResult{Row: 2, Key: "c-2", Error: &BulkRowError{
+		Type:    "validation_error",
+		Message: "There was a validation error on this request",
+		Status:  http.StatusUnprocessableEntity,
+		Fields:  ErrorFields{"last_name": []interface{}{"is invalid"}},
+	}}, res[2])
+	if assert.NotNil(t, res[3].Error) {
+		assert.Equal(t, BulkErrorParse, res[3].Error.Type)
+		assert.Contains(t, res[3].Error.Message, "address.country")
+	}
+	if assert.NotNil(t, res[4].Error) {
+		assert.Equal(t, BulkErrorDuplicateKey, res[4].Error.Type)
+	}
+	assert.Equal(t, BulkCreateResult{Row: 5, Key: "row:5", ApplicantID: srv.applicantID("Eve")}, res[5])
+	if assert.NotNil(t, res[6].Error) {
+		assert.Equal(t, BulkErrorParse, res[6].Error.Type)
+	}
+	assert.Equal(t, BulkCreateResult{Row: 7, Key: "c-7", ApplicantID: "app-existing", Skipped: true}, res[7])
+
+	applicants := srv.createdApplicants()
+	sort.Slice(applicants, func(i, j int) bool { return applicants[i].FirstName < applicants[j].FirstName })
+	if assert.Len(t, applicants, 2) {
+		assert.Equal(t, Address{Street: "Main Street", Town: "London", Postcode: "SW4 6EH", Country: "GBR"}, applicants[1].Address)
+		assert.Equal(t, "jane@example.com", applicants[1].Email)
+	}
+
+	completed, err := ReadBulkResults(&results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]string{"c-1": srv.applicantID("Jane"), "row:5": srv.applicantID("Eve"), "c-7": "app-existing"}, completed)
+}
+
+func TestBulkCreateApplicants_JSONL(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	source := `{"reference": "c-1", "first_name": "Jane", "last_name": "Doe", "id_numbers": [{"type": "ssn", "value": "123-45-6789"}]}
+
+{"reference": "c-3", "first_name": "John", "surname": "Doe"}
+{"reference": "c-4", "first_name": "Ann"}
+not json
+`
+	var results bytes.Buffer
+	summary, err := client.BulkCreateApplicants(context.Background(), strings.NewReader(source), BulkCreateOptions{
+		Format:      BulkFormatJSONL,
+		Concurrency: 2,
+		ValidateFor: []ReportName{ReportNameDocument},
+		Results:     &results,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &BulkCreateSummary{Created: 1, Failed: 3}, summary)
+
+	res := readResults(t, &results)
+	assert.Equal(t, srv.applicantID("Jane"), res[1].ApplicantID)
+	if assert.NotNil(t, res[3].Error) {
+		assert.Equal(t, BulkErrorParse, res[3].Error.Type)
+		assert.Contains(t, res[3].Error.Message, "surname")
+	}
+	if assert.NotNil(t, res[4].Error) {
+		assert.Equal(t, BulkErrorValidation, res[4].Error.Type)
+		assert.Equal(t, ErrorFields{"last_name": []interface{}{"is required"}}, res[4].Error.Fields)
+	}
+	if assert.NotNil(t, res[5].Error) {
+		assert.Equal(t, BulkErrorParse, res[5].Error.Type)
+	}
+
+	if applicants := srv.createdApplicants(); assert.Len(t, applicants, 1) {
+		assert.Equal(t, []IDNumber{{Type: IDNumberTypeSSN, Value: "123-45-6789"}}, applicants[0].IDNumbers)
+	}
+}
+
+func TestBulkCreateApplicants_Errors(t *testing.T) {
+	client := NewClient("123").(*client)
+	ctx := context.Background()
+
+	_, err := client.BulkCreateApplicants(ctx, strings.NewReader(""), BulkCreateOptions{})
+	assert.Equal(t, ErrUnknownBulkFormat, err)
+
+	_, err = client.BulkCreateApplicants(ctx, strings.NewReader("first_name,surname\n"), BulkCreateOptions{Format: BulkFormatCSV})
+	assert.True(t, errors.Is(err, ErrUnknownBulkColumn))
+	assert.Contains(t, err.Error(), "surname")
+}
+
+func TestBulkCreateApplicants_Cancelled(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.BulkCreateApplicants(ctx, strings.NewReader("first_name\nJane\nJohn\n"), BulkCreateOptions{
+		Format:            BulkFormatCSV,
+		RequestsPerSecond: 10,
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, srv.createdApplicants())
+}
+
+// cancellingRequester performs requests, then cancels the import once a
+// response has been received.
+type cancellingRequester struct {
+	cancel context.CancelFunc
+}
+
+func (r cancellingRequester) Do(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.cancel()
+	return resp, nil
+}
+
+func TestBulkCreateApplicants_CancelledAfterCreate(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+	client.SetHTTPClient(cancellingRequester{cancel})
+
+	var results bytes.Buffer
+	summary, err := client.BulkCreateApplicants(ctx, strings.NewReader("reference,first_name\nc-1,Jane\nc-2,John\n"), BulkCreateOptions{
+		Format:      BulkFormatCSV,
+		Concurrency: 1,
+		Results:     &results,
+	})
+	assert.Equal(t, context.Canceled, err)
+
+	// The applicant created as the import was cancelled is recorded
+	assert.Len(t, srv.createdApplicants(), 1)
+	assert.Equal(t, 1, summary.Created)
+	completed, err := ReadBulkResults(&results)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"c-1": srv.applicantID("Jane")}, completed)
+}
+
+func TestBulkCreateApplicants_RequireReference(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	var results bytes.Buffer
+	summary, err := client.BulkCreateApplicants(context.Background(), strings.NewReader("reference,first_name,last_name\nc-1,Jane,Doe\n,John,Doe\n"), BulkCreateOptions{
+		Format:           BulkFormatCSV,
+		Results:          &results,
+		RequireReference: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &BulkCreateSummary{Created: 1, Failed: 1}, summary)
+	assert.Len(t, srv.createdApplicants(), 1)
+
+	res := readResults(t, &results)
+	if assert.NotNil(t, res[2].Error) {
+		assert.Equal(t, BulkErrorMissingReference, res[2].Error.Type)
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	for _, perSecond := range []float64{0, -1, math.NaN()} {
+		assert.Nil(t, newRateLimiter(perSecond), "%v", perSecond)
+	}
+	// Rates beyond what a ticker can represent are clamped rather than panicking
+	for _, perSecond := range []float64{2e9, 1e300, math.Inf(1), 1e-300} {
+		l := newRateLimiter(perSecond)
+		if assert.NotNil(t, l, "%v", perSecond) {
+			l.stop()
+		}
+	}
+}
@@ -0,0 +1,466 @@
+package onfido
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Applicant dedup errors
+var (
+	// ErrApplicantCreationPending is returned by
+	// ApplicantDeduper.CreateApplicant when an applicant is already being
+	// created for the same reference. The call should be retried once that
+	// creation has had time to finish.
+	ErrApplicantCreationPending = errors.New("applicant creation for reference already in progress")
+	// ErrApplicantNotIndexed is returned by ApplicantDeduper.CreateApplicant
+	// with the created applicant when it couldn't be recorded under its
+	// reference. The reference stays reserved until
+	// ApplicantDeduper.RecordApplicant records it.
+	ErrApplicantNotIndexed = errors.New("created applicant could not be recorded in the applicant index")
+)
+
+// ApplicantIndex maps keys identifying a customer to the ID of the
+// applicant created for them, see ApplicantIndexKeys.
+type ApplicantIndex interface {
+	// Lookup returns the applicant ID recorded for the key, if any.
+	Lookup(ctx context.Context, key string) (string, bool, error)
+	// Put records the applicant ID for the key, replacing any previous one.
+	Put(ctx context.Context, key, applicantID string) error
+	// PutIfAbsent records the applicant ID for the key unless the key is
+	// already recorded, and reports whether it was recorded. It must be
+	// atomic across every process sharing the index, e.g. an insert
+	// which fails on a unique key.
+	PutIfAbsent(ctx context.Context, key, applicantID string) (bool, error)
+	// Replace records the applicant ID for the key if old is still
+	// recorded for it, and reports whether it was recorded. Like
+	// PutIfAbsent it must be atomic, e.g. an update conditional on the
+	// old value.
+	Replace(ctx context.Context, key, old, applicantID string) (bool, error)
+	// Delete removes the key if applicantID is still recorded for it, and
+	// reports whether it was removed. Like PutIfAbsent it must be atomic.
+	Delete(ctx context.Context, key, applicantID string) (bool, error)
+}
+
+// applicantRefKeyPrefix prefixes the index keys of external references.
+const applicantRefKeyPrefix = "ref:"
+
+// ApplicantIndexKeys returns the keys identifying the customer an
+// applicant is created for: their external reference, their normalised
+// email, and their normalised name and date of birth. Keys whose fields
+// aren't set are omitted.
+func ApplicantIndexKeys(reference string, a Applicant) []string {
+	var keys []string
+	if ref := strings.TrimSpace(reference); ref != "" {
+		keys = append(keys, applicantRefKeyPrefix+ref)
+	}
+	if email := strings.ToLower(strings.TrimSpace(a.Email)); email != "" {
+		keys = append(keys, "email:"+email)
+	}
+	first, last := normaliseName(a.FirstName), normaliseName(a.LastName)
+	if first != "" && last != "" && a.DOB != "" {
+		keys = append(keys, "name_dob:"+first+"|"+last+"|"+strings.TrimSpace(a.DOB))
+	}
+	return keys
+}
+
+// MemoryApplicantIndex is an in-memory ApplicantIndex.
+type MemoryApplicantIndex struct {
+	mu   sync.RWMutex
+	keys map[string]string
+}
+
+var _ ApplicantIndex = &MemoryApplicantIndex{}
+
+// NewMemoryApplicantIndex creates a new empty in-memory index.
+func NewMemoryApplicantIndex() *MemoryApplicantIndex {
+	return &MemoryApplicantIndex{keys: make(map[string]string)}
+}
+
+// Lookup implements ApplicantIndex.
+func (idx *MemoryApplicantIndex) Lookup(_ context.Context, key string) (string, bool, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	id, ok := idx.keys[key]
+	return id, ok, nil
+}
+
+// Put implements ApplicantIndex.
+func (idx *MemoryApplicantIndex) Put(_ context.Context, key, applicantID string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.keys[key] = applicantID
+	return nil
+}
+
+// PutIfAbsent implements ApplicantIndex.
+func (idx *MemoryApplicantIndex) PutIfAbsent(_ context.Context, key, applicantID string) (bool, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if _, ok := idx.keys[key]; ok {
+		return false, nil
+	}
+	idx.keys[key] = applicantID
+	return true, nil
+}
+
+// Replace implements ApplicantIndex.
+func (idx *MemoryApplicantIndex) Replace(_ context.Context, key, old, applicantID string) (bool, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if id, ok := idx.keys[key]; !ok || id != old {
+		return false, nil
+	}
+	idx.keys[key] = applicantID
+	return true, nil
+}
+
+// Delete implements ApplicantIndex.
+func (idx *MemoryApplicantIndex) Delete(_ context.Context, key, applicantID string) (bool, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if id, ok := idx.keys[key]; !ok || id != applicantID {
+		return false, nil
+	}
+	delete(idx.keys, key)
+	return true, nil
+}
+
+// defaultApplicantReservationTimeout is how long a reference stays
+// reserved by a creation which never finished if
+// ApplicantDeduper.ReservationTimeout isn't set.
+const defaultApplicantReservationTimeout = time.Minute
+
+// applicantIndexAttempts is the number of times a created applicant's
+// reference is recorded before giving up, waiting applicantIndexBackoff
+// before the first retry and doubling it for each one.
+const (
+	applicantIndexAttempts = 4
+	applicantIndexBackoff  = 50 * time.Millisecond
+)
+
+// applicantReservationPrefix prefixes the index value reserving a
+// reference while its applicant is created, followed by when it was made.
+const applicantReservationPrefix = "pending:"
+
+// applicantReservation returns the index value reserving a reference at t.
+func applicantReservation(t time.Time) string {
+	return applicantReservationPrefix + strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// parseApplicantReservation returns when a reservation was made, and
+// whether the index value is one rather than an applicant ID.
+func parseApplicantReservation(v string) (time.Time, bool) {
+	if !strings.HasPrefix(v, applicantReservationPrefix) {
+		return time.Time{}, false
+	}
+	ns, _ := strconv.ParseInt(strings.TrimPrefix(v, applicantReservationPrefix), 10, 64)
+	return time.Unix(0, ns), true
+}
+
+// ApplicantDeduper creates applicants unless one already exists for the
+// same customer, so that retried onboarding doesn't create duplicates. It
+// is safe for concurrent use, and by several processes sharing an index.
+type ApplicantDeduper struct {
+	// Keys returns the index keys of an applicant, ApplicantIndexKeys if
+	// nil. Keys of the reference must start with "ref:", and are only
+	// returned when a reference is given.
+	Keys func(reference string, a Applicant) []string
+	// ReservationTimeout is how long a reference stays reserved by a
+	// creation which never finished, e.g. because its process crashed.
+	// A minute if not set.
+	ReservationTimeout time.Duration
+
+	client OnfidoClient
+	index  ApplicantIndex
+}
+
+// NewApplicantDeduper creates a new deduper creating applicants with
+// client and recording them in index.
+func NewApplicantDeduper(client OnfidoClient, index ApplicantIndex) *ApplicantDeduper {
+	return &ApplicantDeduper{client: client, index: index}
+}
+
+// keys returns the applicant's reference keys and its other keys.
+func (d *ApplicantDeduper) keys(reference string, a Applicant) (refKeys, otherKeys []string) {
+	keys := ApplicantIndexKeys(reference, a)
+	if d.Keys != nil {
+		keys = d.Keys(reference, a)
+	}
+	for _, key := range keys {
+		if strings.HasPrefix(key, applicantRefKeyPrefix) {
+			refKeys = append(refKeys, key)
+		} else {
+			otherKeys = append(otherKeys, key)
+		}
+	}
+	return refKeys, otherKeys
+}
+
+func (d *ApplicantDeduper) reservationTimeout() time.Duration {
+	if d.ReservationTimeout > 0 {
+		return d.ReservationTimeout
+	}
+	return defaultApplicantReservationTimeout
+}
+
+// CreateApplicant returns the existing applicant for the customer,
+// otherwise it creates the applicant. It reports whether the applicant
+// already existed.
+//
+// A reference, if given, alone identifies the customer: the applicant
+// recorded under it is returned, or a new applicant is created even if
+// one matches the applicant's other keys. The reference is reserved in the
+// index while its applicant is created, so that concurrent calls for it
+// create a single applicant; the others return ErrApplicantCreationPending.
+// If the created applicant can't be recorded under the reference it is
+// returned with ErrApplicantNotIndexed, and the reference stays reserved
+// rather than expiring into a duplicate.
+//
+// Without a reference, the applicant recorded under any of the other keys
+// is returned, provided every one of those keys is also a key of that
+// applicant's own data, so that e.g. customers sharing an email address
+// aren't matched with each other.
+//
+// Applicants are recorded under the keys of their own data, without
+// replacing keys recorded for other applicants. Index entries for
+// applicants which have since been deleted are removed.
+// see https://documentation.onfido.com/?shell#create-applicant
+func (d *ApplicantDeduper) CreateApplicant(ctx context.Context, reference string, a Applicant) (*Applicant, bool, error) {
+	refKeys, otherKeys := d.keys(reference, a)
+	if len(refKeys) == 0 {
+		existing, err := d.match(ctx, otherKeys)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing != nil {
+			return existing, true, d.record(ctx, existing)
+		}
+		created, err := d.client.CreateApplicant(ctx, a)
+		if err != nil {
+			return nil, false, err
+		}
+		return created, false, d.record(ctx, created)
+	}
+
+	refKey := refKeys[0]
+	existing, reservation, err := d.reserve(ctx, refKey)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, true, d.record(ctx, existing)
+	}
+
+	created, err := d.client.CreateApplicant(ctx, a)
+	if err != nil {
+		if _, dErr := d.index.Delete(ctx, refKey, reservation); dErr != nil {
+			return nil, false, fmt.Errorf("%w (releasing applicant index reservation: %v)", err, dErr)
+		}
+		return nil, false, err
+	}
+	if err := d.fulfil(ctx, refKey, reservation, created.ID); err != nil {
+		return created, false, fmt.Errorf("%w: %v", ErrApplicantNotIndexed, err)
+	}
+	return created, false, d.recordReferences(ctx, refKeys[1:], created)
+}
+
+// RecordApplicant records the applicant under the keys of the reference
+// and of its data, replacing the reference's previous applicant or
+// reservation. It records applicants created outside the deduper, and
+// those returned with ErrApplicantNotIndexed.
+func (d *ApplicantDeduper) RecordApplicant(ctx context.Context, reference string, a *Applicant) error {
+	refKeys, _ := d.keys(reference, *a)
+	if len(refKeys) > 0 {
+		if err := d.index.Put(ctx, refKeys[0], a.ID); err != nil {
+			return fmt.Errorf("updating applicant index: %w", err)
+		}
+		refKeys = refKeys[1:]
+	}
+	return d.recordReferences(ctx, refKeys, a)
+}
+
+// recordReferences records the applicant under the additional reference
+// keys and the keys of its data which aren't already recorded.
+func (d *ApplicantDeduper) recordReferences(ctx context.Context, refKeys []string, a *Applicant) error {
+	for _, key := range refKeys {
+		if _, err := d.index.PutIfAbsent(ctx, key, a.ID); err != nil {
+			return fmt.Errorf("updating applicant index: %w", err)
+		}
+	}
+	return d.record(ctx, a)
+}
+
+// fulfil replaces the reservation of the reference key with the created
+// applicant's ID, retrying with backoff so that a transient index failure
+// doesn't leave the reservation to expire. If the reservation has already
+// been taken over the ID is recorded regardless.
+func (d *ApplicantDeduper) fulfil(ctx context.Context, key, reservation, applicantID string) error {
+	backoff := applicantIndexBackoff
+	var err error
+	for attempt := 0; attempt < applicantIndexAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return err
+			}
+			backoff *= 2
+		}
+
+		var replaced bool
+		if replaced, err = d.index.Replace(ctx, key, reservation, applicantID); err == nil {
+			if !replaced {
+				err = d.index.Put(ctx, key, applicantID)
+			}
+			if err == nil {
+				return nil
+			}
+		}
+	}
+	return err
+}
+
+// reserve returns the live applicant recorded under the reference key, or
+// reserves the key for an applicant about to be created and returns the
+// reservation. Keys of deleted applicants and abandoned reservations are
+// replaced only if they haven't changed since they were looked up, so
+// that only one of the processes replacing them reserves the key.
+func (d *ApplicantDeduper) reserve(ctx context.Context, key string) (*Applicant, string, error) {
+	// A few attempts cover the key changing between the lookup and the
+	// reservation, e.g. another process creating or deleting it
+	for attempt := 0; attempt < 3; attempt++ {
+		id, ok, err := d.index.Lookup(ctx, key)
+		if err != nil {
+			return nil, "", fmt.Errorf("looking up applicant index: %w", err)
+		}
+		if ok {
+			if at, pending := parseApplicantReservation(id); pending {
+				if time.Since(at) < d.reservationTimeout() {
+					return nil, "", ErrApplicantCreationPending
+				}
+			} else {
+				a, err := d.live(ctx, id)
+				if err != nil || a != nil {
+					return a, "", err
+				}
+			}
+		}
+
+		reservation := applicantReservation(time.Now())
+		var reserved bool
+		if ok {
+			reserved, err = d.index.Replace(ctx, key, id, reservation)
+		} else {
+			reserved, err = d.index.PutIfAbsent(ctx, key, reservation)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("updating applicant index: %w", err)
+		}
+		if reserved {
+			return nil, reservation, nil
+		}
+	}
+	return nil, "", ErrApplicantCreationPending
+}
+
+// match returns the first live applicant recorded under any of the keys
+// whose own keys include all of them.
+func (d *ApplicantDeduper) match(ctx context.Context, keys []string) (*Applicant, error) {
+	for _, key := range keys {
+		id, ok, err := d.index.Lookup(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("looking up applicant index: %w", err)
+		}
+		if !ok {
+			continue
+		}
+
+		a, err := d.live(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if a == nil {
+			if _, err := d.index.Delete(ctx, key, id); err != nil {
+				return nil, fmt.Errorf("updating applicant index: %w", err)
+			}
+			continue
+		}
+		if d.hasKeys(*a, keys) {
+			return a, nil
+		}
+	}
+	return nil, nil
+}
+
+// live returns the applicant, or nil if it has been deleted.
+func (d *ApplicantDeduper) live(ctx context.Context, id string) (*Applicant, error) {
+	a, err := d.client.GetApplicant(ctx, id)
+	if isNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if a.ScheduledForDeletion() {
+		return nil, nil
+	}
+	return a, nil
+}
+
+// hasKeys reports whether all of the keys are keys of the applicant's data.
+func (d *ApplicantDeduper) hasKeys(a Applicant, keys []string) bool {
+	_, own := d.keys("", a)
+	for _, key := range keys {
+		found := false
+		for _, k := range own {
+			if k == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// record records the applicant under the keys of its data which aren't
+// already recorded.
+func (d *ApplicantDeduper) record(ctx context.Context, a *Applicant) error {
+	_, keys := d.keys("", *a)
+	for _, key := range keys {
+		if _, err := d.index.PutIfAbsent(ctx, key, a.ID); err != nil {
+			return fmt.Errorf("updating applicant index: %w", err)
+		}
+	}
+	return nil
+}
+
+// IndexApplicants walks ListApplicants recording every applicant in the
+// index, returning how many were recorded. Onfido doesn't hold external
+// references, so applicants are only recorded under their other keys.
+// Keys which are already recorded are kept, so where several applicants
+// share a key the first listed is kept.
+func (d *ApplicantDeduper) IndexApplicants(ctx context.Context) (int, error) {
+	n := 0
+	it := d.client.ListApplicants()
+	for it.Next(ctx) {
+		a := it.Applicant()
+		if a.ScheduledForDeletion() {
+			continue
+		}
+		if err := d.record(ctx, a); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := it.Err(); err != nil {
+		return n, fmt.Errorf("listing applicants: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,360 @@
+package onfido
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplicantIndexKeys(t *testing.T) {
+	assert.Equal(t, []string{
+		"ref:cust-1",
+		"email:jane.doe@example.com",
+		"name_dob:JANE ANN|DOE|1990-01-31",
+	}, ApplicantIndexKeys(" cust-1 ", Applicant{
+		Email:     " Jane.Doe@Example.com",
+		FirstName: "Jane  Ann",
+		LastName:  "DOE",
+		DOB:       "1990-01-31",
+	}))
+	assert.Equal(t, []string{"email:jane@example.com"}, ApplicantIndexKeys("", Applicant{
+		Email:     "jane@example.com",
+		FirstName: "Jane",
+		LastName:  "Doe",
+	}))
+	assert.Empty(t, ApplicantIndexKeys("", Applicant{FirstName: "Jane"}))
+}
+
+func TestApplicantDeduper_CreateApplicant(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	ctx := context.Background()
+	index := NewMemoryApplicantIndex()
+	d := NewApplicantDeduper(client, index)
+
+	jane := Applicant{FirstName: "Jane", LastName: "Doe", Email: "jane@example.com", DOB: "1990-01-31"}
+	a, existed, err := d.CreateApplicant(ctx, "cust-1", jane)
+	if assert.NoError(t, err) {
+		assert.False(t, existed)
+		assert.Equal(t, "app-1", a.ID)
+	}
+
+	// Retried with the same reference, the reference alone matches
+	a, existed, err = d.CreateApplicant(ctx, "cust-1", Applicant{FirstName: "Jane", LastName: "Doe", Email: "other@example.com"})
+	if assert.NoError(t, err) {
+		assert.True(t, existed)
+		assert.Equal(t, "app-1", a.ID)
+	}
+	_, ok, _ := index.Lookup(ctx, "email:other@example.com")
+	assert.False(t, ok)
+
+	// Retried without a reference, matched by the applicant's email
+	a, existed, err = d.CreateApplicant(ctx, "", Applicant{FirstName: "Jane", LastName: "Doe", Email: "JANE@example.com "})
+	if assert.NoError(t, err) {
+		assert.True(t, existed)
+		assert.Equal(t, "app-1", a.ID)
+	}
+
+	// Another customer sharing the email is not matched, with or without a reference
+	a, existed, err = d.CreateApplicant(ctx, "cust-2", Applicant{FirstName: "Jim", LastName: "Doe", Email: "jane@example.com"})
+	if assert.NoError(t, err) {
+		assert.False(t, existed)
+		assert.Equal(t, "app-2", a.ID)
+	}
+	a, existed, err = d.CreateApplicant(ctx, "", Applicant{FirstName: "Joe", LastName: "Doe", Email: "jane@example.com", DOB: "2010-05-01"})
+	if assert.NoError(t, err) {
+		assert.False(t, existed)
+		assert.Equal(t, "app-3", a.ID)
+	}
+	id, _, _ := index.Lookup(ctx, "ref:cust-2")
+	assert.Equal(t, "app-2", id)
+	id, _, _ = index.Lookup(ctx, "email:jane@example.com")
+	assert.Equal(t, "app-1", id)
+
+	// Deleted applicants are removed from the index and created again
+	srv.delete("app-1")
+	a, existed, err = d.CreateApplicant(ctx, "cust-1", jane)
+	if assert.NoError(t, err) {
+		assert.False(t, existed)
+		assert.Equal(t, "app-4", a.ID)
+	}
+	id, ok, _ = index.Lookup(ctx, "ref:cust-1")
+	assert.True(t, ok)
+	assert.Equal(t, "app-4", id)
+
+	assert.Equal(t, 4, len(srv.createdApplicants()))
+}
+
+func TestApplicantDeduper_ConcurrentReference(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	ctx := context.Background()
+	d := NewApplicantDeduper(client, NewMemoryApplicantIndex())
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	ids := make([]string, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			a, _, err := d.CreateApplicant(ctx, "cust-1", Applicant{FirstName: "Jane", LastName: "Doe"})
+			errs[i] = err
+			if err == nil {
+				ids[i] = a.ID
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// A single applicant is created, calls racing its creation are told to retry
+	assert.Equal(t, 1, len(srv.createdApplicants()))
+	for i, err := range errs {
+		if err != nil {
+			assert.Equal(t, ErrApplicantCreationPending, err)
+		} else {
+			assert.Equal(t, "app-1", ids[i])
+		}
+	}
+}
+
+func TestApplicantDeduper_Reservations(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	ctx := context.Background()
+	index := NewMemoryApplicantIndex()
+	d := NewApplicantDeduper(client, index)
+
+	assert.NoError(t, index.Put(ctx, "ref:cust-1", applicantReservation(time.Now())))
+	_, _, err := d.CreateApplicant(ctx, "cust-1", Applicant{FirstName: "Jane"})
+	assert.Equal(t, ErrApplicantCreationPending, err)
+	assert.Equal(t, 0, len(srv.createdApplicants()))
+
+	// Reservations of creations which never finished are taken over
+	assert.NoError(t, index.Put(ctx, "ref:cust-1", applicantReservation(time.Now().Add(-2*time.Minute))))
+	a, existed, err := d.CreateApplicant(ctx, "cust-1", Applicant{FirstName: "Jane"})
+	if assert.NoError(t, err) {
+		assert.False(t, existed)
+		assert.Equal(t, "app-1", a.ID)
+	}
+
+	// A failed creation releases its reservation
+	client.endpoint = "http://127.0.0.1:0"
+	_, _, err = d.CreateApplicant(ctx, "cust-2", Applicant{FirstName: "John"})
+	assert.Error(t, err)
+	_, ok, _ := index.Lookup(ctx, "ref:cust-2")
+	assert.False(t, ok)
+}
+
+func TestApplicantDeduper_ConcurrentStaleReservation(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	ctx := context.Background()
+	index := NewMemoryApplicantIndex()
+	d := NewApplicantDeduper(client, index)
+	assert.NoError(t, index.Put(ctx, "ref:cust-1", applicantReservation(time.Now().Add(-2*time.Minute))))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, errs[i] = d.CreateApplicant(ctx, "cust-1", Applicant{FirstName: "Jane", LastName: "Doe"})
+		}(i)
+	}
+	wg.Wait()
+
+	// Only one of the calls takes over the abandoned reservation
+	assert.Equal(t, 1, len(srv.createdApplicants()))
+	for _, err := range errs {
+		if err != nil {
+			assert.Equal(t, ErrApplicantCreationPending, err)
+		}
+	}
+	id, _, _ := index.Lookup(ctx, "ref:cust-1")
+	assert.Equal(t, "app-1", id)
+}
+
+// failingApplicantIndex fails the first failures calls to Replace.
+type failingApplicantIndex struct {
+	*MemoryApplicantIndex
+	failures int
+}
+
+func (idx *failingApplicantIndex) Replace(ctx context.Context, key, old, applicantID string) (bool, error) {
+	if idx.failures > 0 {
+		idx.failures--
+		return false, errors.New("index unavailable")
+	}
+	return idx.MemoryApplicantIndex.Replace(ctx, key, old, applicantID)
+}
+
+func TestApplicantDeduper_IndexFailure(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	ctx := context.Background()
+	index := &failingApplicantIndex{MemoryApplicantIndex: NewMemoryApplicantIndex(), failures: 2}
+	d := NewApplicantDeduper(client, index)
+
+	// Transient failures are retried
+	a, _, err := d.CreateApplicant(ctx, "cust-1", Applicant{FirstName: "Jane", Email: "jane@example.com"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "app-1", a.ID)
+	}
+	id, _, _ := index.Lookup(ctx, "ref:cust-1")
+	assert.Equal(t, "app-1", id)
+
+	// The created applicant is returned when the index keeps failing, and
+	// its reference stays reserved until it's recorded
+	index.failures = applicantIndexAttempts
+	a, _, err = d.CreateApplicant(ctx, "cust-2", Applicant{FirstName: "John", Email: "john@example.com"})
+	assert.True(t, errors.Is(err, ErrApplicantNotIndexed))
+	if assert.NotNil(t, a) {
+		assert.Equal(t, "app-2", a.ID)
+	}
+	id, _, _ = index.Lookup(ctx, "ref:cust-2")
+	_, pending := parseApplicantReservation(id)
+	assert.True(t, pending)
+
+	assert.NoError(t, d.RecordApplicant(ctx, "cust-2", a))
+	id, _, _ = index.Lookup(ctx, "ref:cust-2")
+	assert.Equal(t, "app-2", id)
+	id, _, _ = index.Lookup(ctx, "email:john@example.com")
+	assert.Equal(t, "app-2", id)
+
+	a, existed, err := d.CreateApplicant(ctx, "cust-2", Applicant{FirstName: "John"})
+	if assert.NoError(t, err) {
+		assert.True(t, existed)
+		assert.Equal(t, "app-2", a.ID)
+	}
+	assert.Equal(t, 2, len(srv.createdApplicants()))
+}
+
+func TestMemoryApplicantIndex_ReplaceDelete(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemoryApplicantIndex()
+	assert.NoError(t, index.Put(ctx, "ref:cust-1", "app-1"))
+
+	ok, err := index.Replace(ctx, "ref:cust-1", "app-2", "app-3")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = index.Replace(ctx, "ref:cust-2", "", "app-3")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = index.Replace(ctx, "ref:cust-1", "app-1", "app-3")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = index.Delete(ctx, "ref:cust-1", "app-1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	id, _, _ := index.Lookup(ctx, "ref:cust-1")
+	assert.Equal(t, "app-3", id)
+	ok, err = index.Delete(ctx, "ref:cust-1", "app-3")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	_, found, _ := index.Lookup(ctx, "ref:cust-1")
+	assert.False(t, found)
+}
+
+func TestMemoryApplicantIndex_PutIfAbsent(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemoryApplicantIndex()
+
+	ok, err := index.PutIfAbsent(ctx, "email:jane@example.com", "app-1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = index.PutIfAbsent(ctx, "email:jane@example.com", "app-2")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	id, _, _ := index.Lookup(ctx, "email:jane@example.com")
+	assert.Equal(t, "app-1", id)
+}
+
+func TestApplicantDeduper_IndexApplicants(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+	srv.add(Applicant{FirstName: "Jane", LastName: "Doe", DOB: "1990-01-31"})
+	srv.add(Applicant{FirstName: "John", LastName: "Smith", Email: "john@example.com"})
+	srv.add(Applicant{FirstName: "Johnny", LastName: "Smith", Email: "john@example.com"})
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	ctx := context.Background()
+	d := NewApplicantDeduper(client, NewMemoryApplicantIndex())
+
+	n, err := d.IndexApplicants(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	a, existed, err := d.CreateApplicant(ctx, "", Applicant{FirstName: "jane", LastName: "DOE", DOB: "1990-01-31"})
+	if assert.NoError(t, err) {
+		assert.True(t, existed)
+		assert.Equal(t, "app-1", a.ID)
+	}
+	a, existed, err = d.CreateApplicant(ctx, "", Applicant{FirstName: "John", LastName: "Smith", Email: "john@example.com"})
+	if assert.NoError(t, err) {
+		assert.True(t, existed)
+		assert.Equal(t, "app-2", a.ID)
+	}
+	a, existed, err = d.CreateApplicant(ctx, "", Applicant{FirstName: "Ann", LastName: "Lee", Email: "ann@example.com"})
+	if assert.NoError(t, err) {
+		assert.False(t, existed)
+		assert.Equal(t, "app-4", a.ID)
+	}
+
+	// A reference which isn't indexed isn't matched by the other keys
+	a, existed, err = d.CreateApplicant(ctx, "cust-9", Applicant{FirstName: "Jane", LastName: "Doe", DOB: "1990-01-31"})
+	if assert.NoError(t, err) {
+		assert.False(t, existed)
+		assert.Equal(t, "app-5", a.ID)
+	}
+}
+
+func TestApplicantDeduper_CustomKeys(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	ctx := context.Background()
+	d := NewApplicantDeduper(client, NewMemoryApplicantIndex())
+	d.Keys = func(reference string, a Applicant) []string {
+		return []string{"ref:" + reference}
+	}
+
+	_, _, err := d.CreateApplicant(ctx, "cust-1", Applicant{Email: "jane@example.com"})
+	assert.NoError(t, err)
+	_, existed, err := d.CreateApplicant(ctx, "cust-2", Applicant{Email: "jane@example.com"})
+	assert.NoError(t, err)
+	assert.False(t, existed)
+	assert.Equal(t, 2, len(srv.createdApplicants()))
+}
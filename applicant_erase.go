@@ -0,0 +1,287 @@
+package onfido
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Erasure errors
+var (
+	ErrMissingErasureSigningKey = errors.New("erasure receipt signing key not set")
+	ErrErasureNotVerified       = errors.New("applicant still exists and is not scheduled for deletion")
+	ErrInvalidErasureReceipt    = errors.New("erasure receipt signature doesn't match")
+)
+
+// ApplicantInventory represents everything Onfido holds for an applicant
+type ApplicantInventory struct {
+	Applicant  *Applicant
+	Documents  []*Document
+	LivePhotos []*LivePhoto
+	LiveVideos []*LiveVideo
+	Checks     []*Check
+	Reports    []*Report
+}
+
+// InventoryApplicant lists the applicant's documents, live photos, live
+// videos, checks and the checks' reports. Each check's Reports are
+// expanded, and Reports holds the reports of every check.
+func (c *client) InventoryApplicant(ctx context.Context, id string) (*ApplicantInventory, error) {
+	if id == "" {
+		return nil, ErrInvalidApplicantID
+	}
+
+	a, err := c.GetApplicant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	inv := &ApplicantInventory{Applicant: a}
+
+	docs := c.ListDocuments(id)
+	for docs.Next(ctx) {
+		inv.Documents = append(inv.Documents, docs.Document())
+	}
+	if err := docs.Err(); err != nil {
+		return nil, fmt.Errorf("listing documents: %w", err)
+	}
+
+	photos := c.ListLivePhotos(id)
+	for photos.Next(ctx) {
+		inv.LivePhotos = append(inv.LivePhotos, photos.LivePhoto())
+	}
+	if err := photos.Err(); err != nil {
+		return nil, fmt.Errorf("listing live photos: %w", err)
+	}
+
+	videos := c.ListLiveVideos(id)
+	for videos.Next(ctx) {
+		inv.LiveVideos = append(inv.LiveVideos, videos.LiveVideo())
+	}
+	if err := videos.Err(); err != nil {
+		return nil, fmt.Errorf("listing live videos: %w", err)
+	}
+
+	checks := c.ListChecks(id)
+	for checks.Next(ctx) {
+		inv.Checks = append(inv.Checks, checks.Check())
+	}
+	if err := checks.Err(); err != nil {
+		return nil, fmt.Errorf("listing checks: %w", err)
+	}
+
+	for _, chk := range inv.Checks {
+		chk.Reports = nil
+		reports := c.ListReports(chk.ID)
+		for reports.Next(ctx) {
+			chk.Reports = append(chk.Reports, reports.Report())
+			inv.Reports = append(inv.Reports, reports.Report())
+		}
+		if err := reports.Err(); err != nil {
+			return nil, fmt.Errorf("listing reports of check %s: %w", chk.ID, err)
+		}
+	}
+
+	return inv, nil
+}
+
+// ErasureResourceType represents the type of a resource covered by an erasure
+type ErasureResourceType string
+
+// Erased resource types
+const (
+	ErasureResourceApplicant ErasureResourceType = "applicant"
+	ErasureResourceDocument  ErasureResourceType = "document"
+	ErasureResourceLivePhoto ErasureResourceType = "live_photo"
+	ErasureResourceLiveVideo ErasureResourceType = "live_video"
+	ErasureResourceCheck     ErasureResourceType = "check"
+	ErasureResourceReport    ErasureResourceType = "report"
+)
+
+// ErasedResource identifies a resource covered by an erasure
+type ErasedResource struct {
+	Type ErasureResourceType `json:"type"`
+	ID   string              `json:"id"`
+}
+
+// Resources returns the IDs of every resource in the inventory.
+func (inv *ApplicantInventory) Resources() []ErasedResource {
+	var rs []ErasedResource
+	add := func(t ErasureResourceType, id string) {
+		rs = append(rs, ErasedResource{Type: t, ID: id})
+	}
+	if inv.Applicant != nil {
+		add(ErasureResourceApplicant, inv.Applicant.ID)
+	}
+	for _, d := range inv.Documents {
+		add(ErasureResourceDocument, d.ID)
+	}
+	for _, p := range inv.LivePhotos {
+		add(ErasureResourceLivePhoto, p.ID)
+	}
+	for _, v := range inv.LiveVideos {
+		add(ErasureResourceLiveVideo, v.ID)
+	}
+	for _, chk := range inv.Checks {
+		add(ErasureResourceCheck, chk.ID)
+	}
+	for _, r := range inv.Reports {
+		add(ErasureResourceReport, r.ID)
+	}
+	return rs
+}
+
+// EraseApplicantOptions configures an applicant erasure
+type EraseApplicantOptions struct {
+	// SigningKey signs the erasure receipt with HMAC-SHA256. Required.
+	SigningKey []byte
+	// Archive, if set, is called with the inventory before the applicant
+	// is deleted. The erasure is aborted if it returns an error.
+	Archive func(ctx context.Context, inv *ApplicantInventory) error
+	// Reference is an optional reference recorded on the receipt, such as
+	// the ID of the erasure request.
+	Reference string
+}
+
+// ErasureReceipt records an applicant erasure for compliance purposes. It
+// holds resource IDs only, no personal data.
+type ErasureReceipt struct {
+	ApplicantID string           `json:"applicant_id"`
+	Reference   string           `json:"reference,omitempty"`
+	RequestedAt time.Time        `json:"requested_at"`
+	CompletedAt time.Time        `json:"completed_at"`
+	Resources   []ErasedResource `json:"resources"`
+	Archived    bool             `json:"archived"`
+	// DeleteAt is when Onfido will permanently remove the applicant, if
+	// it reported one.
+	DeleteAt *time.Time `json:"delete_at,omitempty"`
+	// Signature is the hex encoded HMAC-SHA256 of the receipt's canonical
+	// form, see Canonical. It is empty if the deletion wasn't verified.
+	Signature string `json:"signature,omitempty"`
+}
+
+// erasureReceiptPayload fixes the fields and field order of a receipt's
+// canonical form, independently of ErasureReceipt.
+type erasureReceiptPayload struct {
+	ApplicantID string           `json:"applicant_id"`
+	Reference   string           `json:"reference,omitempty"`
+	RequestedAt string           `json:"requested_at"`
+	CompletedAt string           `json:"completed_at"`
+	Resources   []ErasedResource `json:"resources"`
+	Archived    bool             `json:"archived"`
+	DeleteAt    string           `json:"delete_at,omitempty"`
+}
+
+// Canonical returns the bytes the receipt's signature is computed over, so
+// it can be verified outside Go. It is the compact JSON object with the
+// keys applicant_id, reference (omitted if empty), requested_at,
+// completed_at, resources (an array of objects with the keys type and id),
+// archived and delete_at (omitted if unset), in that order and without the
+// signature. Times are UTC in RFC 3339 format with nanoseconds.
+func (r ErasureReceipt) Canonical() ([]byte, error) {
+	p := erasureReceiptPayload{
+		ApplicantID: r.ApplicantID,
+		Reference:   r.Reference,
+		RequestedAt: r.RequestedAt.UTC().Format(time.RFC3339Nano),
+		CompletedAt: r.CompletedAt.UTC().Format(time.RFC3339Nano),
+		Resources:   r.Resources,
+		Archived:    r.Archived,
+	}
+	if p.Resources == nil {
+		p.Resources = []ErasedResource{}
+	}
+	if r.DeleteAt != nil {
+		p.DeleteAt = r.DeleteAt.UTC().Format(time.RFC3339Nano)
+	}
+	return json.Marshal(p)
+}
+
+// sign returns the hex HMAC-SHA256 of the receipt's canonical form.
+func (r ErasureReceipt) sign(key []byte) (string, error) {
+	data, err := r.Canonical()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Verify checks the receipt's signature was made with key.
+func (r *ErasureReceipt) Verify(key []byte) error {
+	want, err := r.sign(key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(want), []byte(r.Signature)) {
+		return ErrInvalidErasureReceipt
+	}
+	return nil
+}
+
+// EraseApplicant erases everything Onfido holds for an applicant: it
+// inventories the applicant's resources, optionally archives them, deletes
+// the applicant, verifies the deletion and returns a signed receipt.
+// Deleting an applicant deletes all of its resources. If the applicant is
+// deleted but the deletion can't be verified, the unsigned receipt is
+// returned along with the error, so that the deletion is still recorded.
+// see https://documentation.onfido.com/?shell#delete-applicant
+func (c *client) EraseApplicant(ctx context.Context, id string, opts EraseApplicantOptions) (*ErasureReceipt, error) {
+	if len(opts.SigningKey) == 0 {
+		return nil, ErrMissingErasureSigningKey
+	}
+
+	receipt := &ErasureReceipt{
+		ApplicantID: id,
+		Reference:   opts.Reference,
+		RequestedAt: time.Now().UTC(),
+	}
+
+	inv, err := c.InventoryApplicant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	receipt.Resources = inv.Resources()
+
+	if opts.Archive != nil {
+		if err := opts.Archive(ctx, inv); err != nil {
+			return nil, fmt.Errorf("archiving applicant: %w", err)
+		}
+		receipt.Archived = true
+	}
+
+	if err := c.DeleteApplicant(ctx, id); err != nil {
+		return nil, err
+	}
+
+	deleteAt, err := c.verifyApplicantDeleted(ctx, id)
+	receipt.CompletedAt = time.Now().UTC()
+	if err != nil {
+		return receipt, fmt.Errorf("verifying applicant deletion: %w", err)
+	}
+	receipt.DeleteAt = deleteAt
+
+	if receipt.Signature, err = receipt.sign(opts.SigningKey); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// verifyApplicantDeleted checks the applicant is gone or scheduled for
+// deletion, returning when it will be removed if known.
+func (c *client) verifyApplicantDeleted(ctx context.Context, id string) (*time.Time, error) {
+	a, err := c.GetApplicant(ctx, id)
+	if isNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if !a.ScheduledForDeletion() {
+		return nil, ErrErasureNotVerified
+	}
+	return a.DeleteAt, nil
+}
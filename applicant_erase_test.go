@@ -0,0 +1,228 @@
+package onfido
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInventoryApplicant(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+	srv.add(Applicant{FirstName: "Jane"})
+	srv.addResource("documents", "app-1", "doc-1")
+	srv.addResource("live_photos", "app-1", "photo-1")
+	srv.addResource("live_videos", "app-1", "video-1")
+	srv.setCheck("check-1", "app-1", CheckStatusComplete,
+		&Report{ID: "report-1", Name: ReportNameDocument},
+		&Report{ID: "report-2", Name: ReportNameFacialSimilarityPhoto})
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	inv, err := client.InventoryApplicant(context.Background(), "app-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "app-1", inv.Applicant.ID)
+	assert.Len(t, inv.Documents, 1)
+	assert.Len(t, inv.LivePhotos, 1)
+	assert.Len(t, inv.LiveVideos, 1)
+
+	// Each check's reports are expanded, as well as collected in Reports
+	if assert.Len(t, inv.Checks, 1) && assert.Len(t, inv.Checks[0].Reports, 2) {
+		assert.Equal(t, "report-1", inv.Checks[0].Reports[0].ID)
+		assert.Equal(t, ReportNameFacialSimilarityPhoto, inv.Checks[0].Reports[1].Name)
+	}
+	assert.Equal(t, inv.Checks[0].Reports, inv.Reports)
+
+	_, err = client.InventoryApplicant(context.Background(), "")
+	assert.Equal(t, ErrInvalidApplicantID, err)
+}
+
+func TestEraseApplicant(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+	srv.add(Applicant{FirstName: "Jane"})
+	srv.addResource("documents", "app-1", "doc-1")
+	srv.addResource("live_photos", "app-1", "photo-1")
+	srv.addResource("live_videos", "app-1", "video-1")
+	srv.setCheck("check-1", "app-1", CheckStatusComplete,
+		&Report{ID: "report-1", Name: ReportNameDocument},
+		&Report{ID: "report-2", Name: ReportNameFacialSimilarityPhoto})
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	key := []byte("secret")
+	var archived *ApplicantInventory
+	receipt, err := client.EraseApplicant(context.Background(), "app-1", EraseApplicantOptions{
+		SigningKey: key,
+		Reference:  "dsr-42",
+		Archive: func(ctx context.Context, inv *ApplicantInventory) error {
+			archived = inv
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, srv.isDeleted("app-1"))
+	if assert.NotNil(t, archived) {
+		assert.Equal(t, "Jane", archived.Applicant.FirstName)
+	}
+	assert.Equal(t, "app-1", receipt.ApplicantID)
+	assert.Equal(t, "dsr-42", receipt.Reference)
+	assert.True(t, receipt.Archived)
+	assert.Nil(t, receipt.DeleteAt)
+	assert.False(t, receipt.CompletedAt.Before(receipt.RequestedAt))
+	assert.Equal(t, []ErasedResource{
+		{ErasureResourceApplicant, "app-1"},
+		{ErasureResourceDocument, "doc-1"},
+		{ErasureResourceLivePhoto, "photo-1"},
+		{ErasureResourceLiveVideo, "video-1"},
+		{ErasureResourceCheck, "check-1"},
+		{ErasureResourceReport, "report-1"},
+		{ErasureResourceReport, "report-2"},
+	}, receipt.Resources)
+
+	// The receipt survives a JSON round trip and detects tampering
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ErasureReceipt
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, decoded.Verify(key))
+	assert.Equal(t, ErrInvalidErasureReceipt, decoded.Verify([]byte("other")))
+	decoded.Resources = decoded.Resources[:1]
+	assert.Equal(t, ErrInvalidErasureReceipt, decoded.Verify(key))
+}
+
+func TestEraseApplicant_ScheduledDeletion(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+	srv.deleteAt = "2020-06-01T12:00:00Z"
+	srv.add(Applicant{FirstName: "Jane"})
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	receipt, err := client.EraseApplicant(context.Background(), "app-1", EraseApplicantOptions{SigningKey: []byte("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, receipt.Archived)
+	if assert.NotNil(t, receipt.DeleteAt) {
+		assert.Equal(t, 2020, receipt.DeleteAt.Year())
+	}
+}
+
+func TestEraseApplicant_ArchiveFailureAborts(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+	srv.add(Applicant{FirstName: "Jane"})
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	archiveErr := errors.New("bucket unavailable")
+	_, err := client.EraseApplicant(context.Background(), "app-1", EraseApplicantOptions{
+		SigningKey: []byte("secret"),
+		Archive: func(ctx context.Context, inv *ApplicantInventory) error {
+			return archiveErr
+		},
+	})
+	assert.True(t, errors.Is(err, archiveErr))
+	assert.False(t, srv.isDeleted("app-1"))
+}
+
+func TestEraseApplicant_MissingSigningKey(t *testing.T) {
+	client := NewClient("123").(*client)
+	_, err := client.EraseApplicant(context.Background(), "app-1", EraseApplicantOptions{})
+	assert.Equal(t, ErrMissingErasureSigningKey, err)
+}
+
+func TestEraseApplicant_NotVerified(t *testing.T) {
+	deleted := false
+	m := mux.NewRouter()
+	m.HandleFunc("/applicants/{id}", func(w http.ResponseWriter, r *http.Request) {
+		// The applicant is still returned, without a deletion scheduled
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"id": "app-1"}`))
+		assert.NoError(t, err)
+	}).Methods("GET")
+	m.HandleFunc("/applicants/{id}", func(w http.ResponseWriter, r *http.Request) {
+		deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("DELETE")
+	for path, key := range map[string]string{"/documents": "documents", "/live_photos": "live_photos", "/live_videos": "live_videos", "/checks": "checks"} {
+		body := `{"` + key + `": []}`
+		m.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, err := w.Write([]byte(body))
+			assert.NoError(t, err)
+		})
+	}
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	receipt, err := client.EraseApplicant(context.Background(), "app-1", EraseApplicantOptions{SigningKey: []byte("secret")})
+	assert.True(t, errors.Is(err, ErrErasureNotVerified))
+	assert.True(t, deleted)
+
+	// The deletion is still recorded, but the receipt isn't signed
+	if assert.NotNil(t, receipt) {
+		assert.Equal(t, "app-1", receipt.ApplicantID)
+		assert.Equal(t, []ErasedResource{{ErasureResourceApplicant, "app-1"}}, receipt.Resources)
+		assert.False(t, receipt.CompletedAt.IsZero())
+		assert.Empty(t, receipt.Signature)
+		assert.Equal(t, ErrInvalidErasureReceipt, receipt.Verify([]byte("secret")))
+	}
+}
+
+func TestErasureReceipt_Canonical(t *testing.T) {
+	deleteAt := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	r := ErasureReceipt{
+		ApplicantID: "app-1",
+		RequestedAt: time.Date(2020, 5, 1, 9, 30, 0, 500, time.FixedZone("CEST", 2*60*60)),
+		CompletedAt: time.Date(2020, 5, 1, 7, 31, 0, 0, time.UTC),
+		Resources:   []ErasedResource{{ErasureResourceApplicant, "app-1"}},
+		DeleteAt:    &deleteAt,
+		Signature:   "ignored",
+	}
+	data, err := r.Canonical()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `{"applicant_id":"app-1","requested_at":"2020-05-01T07:30:00.0000005Z","completed_at":"2020-05-01T07:31:00Z",`+
+		`"resources":[{"type":"applicant","id":"app-1"}],"archived":false,"delete_at":"2020-06-01T12:00:00Z"}`, string(data))
+
+	// The signature is the HMAC of exactly those bytes
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(data)
+	r.Signature = hex.EncodeToString(mac.Sum(nil))
+	assert.NoError(t, r.Verify([]byte("secret")))
+
+	data, err = ErasureReceipt{ApplicantID: "app-2", Reference: "dsr-1"}.Canonical()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `{"applicant_id":"app-2","reference":"dsr-1","requested_at":"0001-01-01T00:00:00Z","completed_at":"0001-01-01T00:00:00Z","resources":[],"archived":false}`, string(data))
+}
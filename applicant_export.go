@@ -0,0 +1,229 @@
+package onfido
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// exportConcurrency is how many files ExportApplicant downloads at once.
+const exportConcurrency = 4
+
+// ExportManifestFile represents a file in an applicant export
+type ExportManifestFile struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ExportManifest represents the manifest.json of an applicant export
+type ExportManifest struct {
+	ApplicantID string               `json:"applicant_id"`
+	ExportedAt  time.Time            `json:"exported_at"`
+	Files       []ExportManifestFile `json:"files"`
+}
+
+// exportFile is a file to be written to an export, data is fetched by
+// download if it isn't set.
+type exportFile struct {
+	name     string
+	data     []byte
+	download func(ctx context.Context, w io.Writer) error
+}
+
+// ExportApplicant writes a zip archive of everything Onfido holds for an
+// applicant to w: applicant.json, the binaries of each document, live
+// photo and live video, each check with its reports expanded as JSON, the
+// PDF of each complete check, and a manifest.json listing the SHA-256
+// checksum of every other file. Files are downloaded concurrently and
+// written in order as they arrive, so only the files being downloaded are
+// held in memory. If an error is returned, what has been written to w is
+// not a valid archive.
+func (c *client) ExportApplicant(ctx context.Context, id string, w io.Writer) error {
+	inv, err := c.InventoryApplicant(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	files, err := exportFiles(inv, c)
+	if err != nil {
+		return err
+	}
+
+	manifest := ExportManifest{ApplicantID: id, ExportedAt: time.Now().UTC()}
+	zw := zip.NewWriter(w)
+	err = streamExportFiles(ctx, files, func(name string, r io.Reader) error {
+		fw, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		h := sha256.New()
+		n, err := io.Copy(io.MultiWriter(fw, h), r)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, ExportManifestFile{
+			Name:   name,
+			Size:   int(n),
+			SHA256: hex.EncodeToString(h.Sum(nil)),
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	fw, err := zw.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// exportFiles lists the files of an export, in the order they are written.
+func exportFiles(inv *ApplicantInventory, c *client) ([]*exportFile, error) {
+	applicant, err := json.MarshalIndent(inv.Applicant, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files := []*exportFile{{name: "applicant.json", data: applicant}}
+
+	for _, d := range inv.Documents {
+		d := d
+		files = append(files, &exportFile{
+			name: exportFileName("documents", d.ID, d.FileName),
+			download: func(ctx context.Context, w io.Writer) error {
+				return c.downloadDocumentTo(ctx, d.ID, w)
+			},
+		})
+	}
+	for _, p := range inv.LivePhotos {
+		p := p
+		files = append(files, &exportFile{
+			name: exportFileName("live_photos", p.ID, p.FileName),
+			download: func(ctx context.Context, w io.Writer) error {
+				return c.downloadLivePhotoTo(ctx, p.ID, w)
+			},
+		})
+	}
+	for _, v := range inv.LiveVideos {
+		v := v
+		files = append(files, &exportFile{
+			name: exportFileName("live_videos", v.ID, v.FileName),
+			download: func(ctx context.Context, w io.Writer) error {
+				return c.downloadLiveVideoTo(ctx, v.ID, w)
+			},
+		})
+	}
+
+	checks := append([]*Check(nil), inv.Checks...)
+	sort.Slice(checks, func(i, j int) bool { return checks[i].ID < checks[j].ID })
+	for _, chk := range checks {
+		chk := chk
+		data, err := json.MarshalIndent(chk, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &exportFile{name: "checks/" + chk.ID + ".json", data: data})
+		if chk.Status != CheckStatusComplete {
+			continue
+		}
+		files = append(files, &exportFile{
+			name: "checks/" + chk.ID + ".pdf",
+			download: func(ctx context.Context, w io.Writer) error {
+				return c.downloadCheckTo(ctx, chk.ID, w)
+			},
+		})
+	}
+	return files, nil
+}
+
+// exportFileName names a file after the resource ID, keeping the
+// extension of its original file name.
+func exportFileName(dir, id, fileName string) string {
+	return dir + "/" + id + path.Ext(path.Base(fileName))
+}
+
+// streamExportFiles passes each file's data to write in order. Files are
+// downloaded exportConcurrency at a time, a download's slot being freed
+// once its file has been written. It stops at the first error.
+func streamExportFiles(ctx context.Context, files []*exportFile, write func(name string, r io.Reader) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	type download struct {
+		data bytes.Buffer
+		err  error
+	}
+	done := make([]chan *download, len(files))
+	for i := range done {
+		done[i] = make(chan *download, 1)
+	}
+	sem := make(chan struct{}, exportConcurrency)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i, f := range files {
+			if f.download == nil {
+				continue
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			wg.Add(1)
+			go func(f *exportFile, done chan<- *download) {
+				defer wg.Done()
+				var dl download
+				dl.err = f.download(ctx, &dl.data)
+				done <- &dl
+			}(f, done[i])
+		}
+	}()
+
+	for i, f := range files {
+		if f.download == nil {
+			if err := write(f.name, bytes.NewReader(f.data)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var dl *download
+		select {
+		case dl = <-done[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if dl.err != nil {
+			return fmt.Errorf("exporting %s: %w", f.name, dl.err)
+		}
+		if err := write(f.name, &dl.data); err != nil {
+			return err
+		}
+		<-sem
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package onfido
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportApplicant(t *testing.T) {
+	const numDocuments = 10
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	download := func(w http.ResponseWriter, data string) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, err := w.Write([]byte(data))
+		assert.NoError(t, err)
+	}
+	writeJSON := func(w http.ResponseWriter, body string) {
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(body))
+		assert.NoError(t, err)
+	}
+
+	var docs []string
+	for i := 0; i < numDocuments; i++ {
+		docs = append(docs, fmt.Sprintf(`{"id": "doc-%d", "file_name": "scan.JPG"}`, i))
+	}
+
+	m := mux.NewRouter()
+	m.HandleFunc("/applicants/{id}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"id": "app-1", "first_name": "Jane"}`)
+	})
+	m.HandleFunc("/documents", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"documents": [`+strings.Join(docs, ",")+`]}`)
+	})
+	m.HandleFunc("/documents/{id}/download", func(w http.ResponseWriter, r *http.Request) {
+		download(w, "document "+mux.Vars(r)["id"])
+	})
+	m.HandleFunc("/live_photos", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"live_photos": [{"id": "photo-1", "file_name": "selfie.png"}]}`)
+	})
+	m.HandleFunc("/live_photos/{id}/download", func(w http.ResponseWriter, r *http.Request) {
+		download(w, "photo")
+	})
+	m.HandleFunc("/live_videos", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"live_videos": [{"id": "video-1", "file_name": "video.mp4"}]}`)
+	})
+	m.HandleFunc("/live_videos/{id}/download", func(w http.ResponseWriter, r *http.Request) {
+		download(w, "video")
+	})
+	m.HandleFunc("/checks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"checks": [{"id": "check-1", "status": "complete"}, {"id": "check-2", "status": "in_progress"}]}`)
+	})
+	m.HandleFunc("/checks/{id}/download", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "check-1", mux.Vars(r)["id"])
+		download(w, "%PDF")
+	})
+	m.HandleFunc("/reports", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"reports": [{"id": "report-`+r.URL.Query().Get("check_id")+`", "name": "document"}]}`)
+	})
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	var buf bytes.Buffer
+	if err := client.ExportApplicant(context.Background(), "app-1", &buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, maxInFlight <= exportConcurrency, "%d concurrent downloads", maxInFlight)
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := make(map[string][]byte)
+	var names []string
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[f.Name] = data
+		names = append(names, f.Name)
+	}
+
+	assert.Equal(t, "applicant.json", names[0])
+	assert.Equal(t, "manifest.json", names[len(names)-1])
+	assert.Equal(t, "document doc-3", string(contents["documents/doc-3.JPG"]))
+	assert.Equal(t, "photo", string(contents["live_photos/photo-1.png"]))
+	assert.Equal(t, "video", string(contents["live_videos/video-1.mp4"]))
+	assert.Equal(t, "%PDF", string(contents["checks/check-1.pdf"]))
+	assert.NotContains(t, contents, "checks/check-2.pdf")
+
+	var chk Check
+	assert.NoError(t, json.Unmarshal(contents["checks/check-2.json"], &chk))
+	if assert.Len(t, chk.Reports, 1) {
+		assert.Equal(t, "report-check-2", chk.Reports[0].ID)
+	}
+
+	var manifest ExportManifest
+	assert.NoError(t, json.Unmarshal(contents["manifest.json"], &manifest))
+	assert.Equal(t, "app-1", manifest.ApplicantID)
+	assert.Len(t, manifest.Files, len(names)-1)
+	for _, f := range manifest.Files {
+		sum := sha256.Sum256(contents[f.Name])
+		assert.Equal(t, hex.EncodeToString(sum[:]), f.SHA256, f.Name)
+		assert.Equal(t, len(contents[f.Name]), f.Size, f.Name)
+	}
+}
+
+func TestExportApplicant_DownloadError(t *testing.T) {
+	m := mux.NewRouter()
+	m.HandleFunc("/applicants/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "app-1"}`))
+	})
+	m.HandleFunc("/documents", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"documents": [{"id": "doc-1"}]}`))
+	})
+	m.HandleFunc("/documents/{id}/download", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	m.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	})
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	var buf bytes.Buffer
+	err := client.ExportApplicant(context.Background(), "app-1", &buf)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "documents/doc-1")
+	}
+	// The files written before the error don't form a valid archive
+	_, err = zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Error(t, err)
+}
+
+// startCountingWriter records how many downloads had started when it was
+// first written to.
+type startCountingWriter struct {
+	bytes.Buffer
+	started      func() int
+	firstStarted int
+}
+
+func (w *startCountingWriter) Write(p []byte) (int, error) {
+	if w.Len() == 0 {
+		w.firstStarted = w.started()
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestExportApplicant_Streams(t *testing.T) {
+	const numDocuments = 12
+	// Incompressible, so that the archive is flushed as it is written
+	data := make([]byte, 16<<10)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	var mu sync.Mutex
+	started := 0
+	var docs []string
+	for i := 0; i < numDocuments; i++ {
+		docs = append(docs, fmt.Sprintf(`{"id": "doc-%d", "file_name": "scan.jpg"}`, i))
+	}
+
+	m := mux.NewRouter()
+	m.HandleFunc("/documents", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"documents": [` + strings.Join(docs, ",") + `]}`))
+	})
+	m.HandleFunc("/documents/{id}/download", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		started++
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write(data)
+	})
+	m.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "app-1"}`))
+	})
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	w := &startCountingWriter{started: func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return started
+	}}
+	if err := client.ExportApplicant(context.Background(), "app-1", w); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writing began before the later documents were downloaded
+	assert.True(t, w.firstStarted <= exportConcurrency, "%d downloads started before the first write", w.firstStarted)
+	assert.Equal(t, numDocuments, started)
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Bytes()), int64(w.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, zr.File, numDocuments+2)
+	for i, f := range zr.File[1 : numDocuments+1] {
+		assert.Equal(t, fmt.Sprintf("documents/doc-%d.jpg", i), f.Name)
+		assert.Equal(t, uint64(len(data)), f.UncompressedSize64)
+	}
+}
@@ -3,9 +3,11 @@ package onfido
 import (
 	"context"
 	"encoding/json"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
@@ -115,6 +117,46 @@ func TestDeleteApplicant_ValidRequest(t *testing.T) {
 	}
 }
 
+func TestRestoreApplicant_NonOKResponse(t *testing.T) {
+	m := mux.NewRouter()
+	m.HandleFunc("/applicants/{id}/restore", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}).Methods("POST")
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	err := client.RestoreApplicant(context.Background(), "65643")
+	if err == nil {
+		t.Fatal()
+	}
+}
+
+func TestRestoreApplicant_ValidRequest(t *testing.T) {
+	expected := "65643"
+
+	m := mux.NewRouter()
+	m.HandleFunc("/applicants/{id}/restore", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		if vars["id"] != expected {
+			t.Fatal("expected applicant id was not in the request")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("POST")
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	err := client.RestoreApplicant(context.Background(), expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestGetApplicant_NonOKResponse(t *testing.T) {
 	m := mux.NewRouter()
 	m.HandleFunc("/applicants/{id}", func(w http.ResponseWriter, r *http.Request) {
@@ -231,6 +273,44 @@ func TestListApplicants_ApplicantsRetrieved(t *testing.T) {
 	}
 }
 
+func TestListApplicantsWithOptions_IncludeDeleted(t *testing.T) {
+	deleteAt := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	expectedJSON, err := json.Marshal(Applicants{
+		Applicants: []*Applicant{
+			{ID: "ce62d838-56f8-4ea5-98be-e7166d1dc33d"},
+			{ID: "7568bd30-e5e5-4dcb-8aea-80d8ecddaf0f", DeleteAt: &deleteAt},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.URL.Query().Get("include_deleted"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, wErr := w.Write(expectedJSON)
+		assert.NoError(t, wErr)
+	}))
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	var deleted []string
+	it := client.ListApplicantsWithOptions(ListApplicantsOptions{IncludeDeleted: true})
+	for it.Next(context.Background()) {
+		if a := it.Applicant(); a.ScheduledForDeletion() {
+			assert.True(t, deleteAt.Equal(*a.DeleteAt))
+			deleted = append(deleted, a.ID)
+		}
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	assert.Equal(t, []string{"7568bd30-e5e5-4dcb-8aea-80d8ecddaf0f"}, deleted)
+}
+
 func TestUpdateApplicant_IDNotSet(t *testing.T) {
 	m := mux.NewRouter()
 	m.HandleFunc("/applicants/{id}", func(w http.ResponseWriter, r *http.Request) {
@@ -304,3 +384,186 @@ func TestUpdateApplicant_ValidRequest(t *testing.T) {
 	assert.Equal(t, expected.FirstName, a.FirstName)
 	assert.Equal(t, expected.LastName, a.LastName)
 }
+
+func TestCreateApplicant_LocationAndConsents(t *testing.T) {
+	m := mux.NewRouter()
+	m.HandleFunc("/applicants", func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.JSONEq(t, `{
+			"first_name": "Jane",
+			"last_name": "Doe",
+			"phone_number": "+15551234567",
+			"location": {"ip_address": "127.0.0.1", "country_of_residence": "USA"},
+			"consents": [
+				{"name": "privacy_notices_read", "granted": true},
+				{"name": "ssn_verification", "granted": false}
+			]
+		}`, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, wErr := w.Write([]byte(`{
+			"id": "ce62d838-56f8-4ea5-98be-e7166d1dc33d",
+			"first_name": "Jane",
+			"last_name": "Doe",
+			"phone_number": "+15551234567",
+			"location": {"ip_address": "127.0.0.1", "country_of_residence": "USA"},
+			"address": {"street": "Main Street", "town": "Springfield", "state": "IL", "postcode": "62701", "country": "USA"}
+		}`))
+		assert.NoError(t, wErr)
+	}).Methods("POST")
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	a, err := client.CreateApplicant(context.Background(), Applicant{
+		FirstName:   "Jane",
+		LastName:    "Doe",
+		PhoneNumber: "+15551234567",
+		Location:    &Location{IPAddress: "127.0.0.1", CountryOfResidence: "USA"},
+		Consents: Consents{
+			{Name: ConsentNamePrivacyNoticesRead, Granted: true},
+			{Name: ConsentNameSSNVerification, Granted: false},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "+15551234567", a.PhoneNumber)
+	assert.Equal(t, &Location{IPAddress: "127.0.0.1", CountryOfResidence: "USA"}, a.Location)
+	assert.Equal(t, "IL", a.Address.State)
+}
+
+func TestApplicant_MarshalJSON_Addresses(t *testing.T) {
+	a := Applicant{
+		FirstName: "Foo",
+		Address:   Address{Street: "Baker Street", Country: "GBR"},
+		Addresses: []Address{{Street: "Old Street", Country: "GBR", EndDate: "2017-12-05"}},
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Applicant
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, a, decoded)
+}
+
+func TestConsents_Granted(t *testing.T) {
+	cs := Consents{
+		{Name: ConsentNamePrivacyNoticesRead, Granted: true},
+		{Name: ConsentNamePhoneNumberVerification, Granted: false},
+	}
+	assert.True(t, cs.Granted(ConsentNamePrivacyNoticesRead))
+	assert.False(t, cs.Granted(ConsentNamePhoneNumberVerification))
+	assert.False(t, cs.Granted(ConsentNameSSNVerification))
+}
+
+func TestUpdateApplicantFields_OnlySetFieldsSent(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch ApplicantPatch
+		body  string
+	}{
+		{
+			name:  "email only",
+			patch: ApplicantPatch{Email: StringPtr("new@example.com")},
+			body:  `{"email": "new@example.com"}`,
+		},
+		{
+			name:  "clear middle name",
+			patch: ApplicantPatch{MiddleName: StringPtr("")},
+			body:  `{"middle_name": ""}`,
+		},
+		{
+			name: "address and location",
+			patch: ApplicantPatch{
+				Address:  &Address{Street: "Main Street", Town: "Springfield", State: "IL", Postcode: "62701", Country: "USA"},
+				Location: &Location{CountryOfResidence: "USA"},
+			},
+			body: `{
+				"address": {
+					"flat_number": "", "building_number": "", "building_name": "",
+					"street": "Main Street", "sub_street": "", "town": "Springfield",
+					"state": "IL", "postcode": "62701", "country": "USA"
+				},
+				"location": {"country_of_residence": "USA"}
+			}`,
+		},
+		{
+			name:  "address history",
+			patch: ApplicantPatch{Addresses: []Address{{Postcode: "SW4 6EH", Country: "GBR"}}},
+			body: `{"addresses": [{
+				"flat_number": "", "building_number": "", "building_name": "",
+				"street": "", "sub_street": "", "town": "",
+				"state": "", "postcode": "SW4 6EH", "country": "GBR"
+			}]}`,
+		},
+		{
+			name:  "clear address history",
+			patch: ApplicantPatch{Addresses: []Address{}},
+			body:  `{"addresses": []}`,
+		},
+		{
+			name:  "clear id numbers",
+			patch: ApplicantPatch{IDNumbers: []IDNumber{}, Consents: Consents{{Name: ConsentNameSSNVerification, Granted: true}}},
+			body:  `{"id_numbers": [], "consents": [{"name": "ssn_verification", "granted": true}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			m := mux.NewRouter()
+			m.HandleFunc("/applicants/{id}", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "65643", mux.Vars(r)["id"])
+				body, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.JSONEq(t, tt.body, string(body))
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				_, wErr := w.Write([]byte(`{"id": "65643"}`))
+				assert.NoError(t, wErr)
+			}).Methods("PUT")
+			srv := httptest.NewServer(m)
+			defer srv.Close()
+
+			client := NewClient("123").(*client)
+			client.endpoint = srv.URL
+
+			a, err := client.UpdateApplicantFields(context.Background(), "65643", tt.patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, "65643", a.ID)
+		})
+	}
+}
+
+func TestUpdateApplicantFields_InvalidRequests(t *testing.T) {
+	client := NewClient("123").(*client)
+
+	_, err := client.UpdateApplicantFields(context.Background(), "", ApplicantPatch{Email: StringPtr("a@b.com")})
+	assert.Equal(t, ErrInvalidApplicantID, err)
+
+	_, err = client.UpdateApplicantFields(context.Background(), "65643", ApplicantPatch{})
+	assert.Equal(t, ErrEmptyApplicantPatch, err)
+}
+
+func TestApplicantPatch_IsEmpty(t *testing.T) {
+	assert.True(t, ApplicantPatch{}.IsEmpty())
+	assert.False(t, ApplicantPatch{Addresses: []Address{}}.IsEmpty())
+	assert.False(t, ApplicantPatch{IDNumbers: []IDNumber{}}.IsEmpty())
+}
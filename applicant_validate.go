@@ -0,0 +1,221 @@
+package onfido
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FieldErrorCode represents why a field failed validation
+type FieldErrorCode string
+
+// Supported field error codes
+const (
+	FieldErrorRequired      FieldErrorCode = "required"
+	FieldErrorInvalidFormat FieldErrorCode = "invalid_format"
+	FieldErrorUnknownValue  FieldErrorCode = "unknown_value"
+)
+
+// FieldError represents an applicant field which failed validation. Field
+// is the field's JSON path, e.g. address.postcode or id_numbers[0].value.
+type FieldError struct {
+	Field   string
+	Code    FieldErrorCode
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// postcodeFormats are the postcode formats of countries which have a
+// well defined one. Postcodes are compared upper-cased.
+var postcodeFormats = map[CountryCode]*regexp.Regexp{
+	"USA": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+	"GBR": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"CAN": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"DEU": regexp.MustCompile(`^\d{5}$`),
+	"FRA": regexp.MustCompile(`^\d{5}$`),
+	"ESP": regexp.MustCompile(`^\d{5}$`),
+	"ITA": regexp.MustCompile(`^\d{5}$`),
+	"NLD": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"IRL": regexp.MustCompile(`^[A-Z\d]{3} ?[A-Z\d]{4}$`),
+	"AUS": regexp.MustCompile(`^\d{4}$`),
+}
+
+// countriesWithoutPostcodes are the countries without a postcode system,
+// whose addresses Onfido accepts without a postcode.
+var countriesWithoutPostcodes = map[CountryCode]bool{
+	"AGO": true, "ATG": true, "ABW": true, "BHS": true, "BLZ": true,
+	"BEN": true, "BWA": true, "BFA": true, "BDI": true, "CMR": true,
+	"CAF": true, "TCD": true, "COM": true, "COG": true, "COD": true,
+	"COK": true, "CIV": true, "DJI": true, "DMA": true, "GNQ": true,
+	"ERI": true, "FJI": true, "GMB": true, "GHA": true, "GRD": true,
+	"GUY": true, "HKG": true, "KIR": true, "PRK": true, "MAC": true,
+	"MWI": true, "MLI": true, "MRT": true, "NRU": true, "NIU": true,
+	"QAT": true, "RWA": true, "KNA": true, "LCA": true, "STP": true,
+	"SYC": true, "SLE": true, "SLB": true, "SOM": true, "SUR": true,
+	"SYR": true, "TLS": true, "TGO": true, "TKL": true, "TON": true,
+	"TUV": true, "UGA": true, "ARE": true, "VUT": true, "YEM": true,
+	"ZWE": true,
+}
+
+// idNumberTypes are the ID number types Onfido accepts.
+var idNumberTypes = map[IDNumberType]bool{
+	IDNumberTypeSSN:             true,
+	IDNumberTypeSocialInsurance: true,
+	IDNumberTypeTaxID:           true,
+	IDNumberTypeIdentityCard:    true,
+	IDNumberTypeDrivingLicense:  true,
+	IDNumberTypeShareCode:       true,
+	IDNumberTypeVoterID:         true,
+	IDNumberTypePassport:        true,
+	IDNumberTypeOther:           true,
+}
+
+// idNumberFormats are the formats of ID number types which have one.
+var idNumberFormats = map[IDNumberType]*regexp.Regexp{
+	IDNumberTypeSSN:             regexp.MustCompile(`^\d{3}-?\d{2}-?\d{4}$`),
+	IDNumberTypeSocialInsurance: regexp.MustCompile(`^\d{3}[ -]?\d{3}[ -]?\d{3}$`),
+}
+
+// ValidateApplicantForReports checks the applicant has the data required
+// by the given reports, and that the data it has is well formed, so
+// invalid applicants can be reported before they are sent to Onfido. It
+// returns nil if the applicant is valid.
+func ValidateApplicantForReports(a Applicant, reports []ReportName) []FieldError {
+	v := &applicantValidator{}
+
+	var needDOB, needAddress bool
+	for _, r := range reports {
+		switch r {
+		case ReportNameIdentityEnhanced:
+			needDOB, needAddress = true, true
+		case ReportNameWatchlistEnhanced:
+			needDOB = true
+		case ReportNameProofOfAddress, ReportNameDocumentWithAddress:
+			needAddress = true
+		}
+	}
+
+	v.required("first_name", a.FirstName)
+	v.required("last_name", a.LastName)
+	if needDOB {
+		v.required("dob", a.DOB)
+	}
+	if a.DOB != "" {
+		v.dob("dob", a.DOB)
+	}
+
+	if needAddress && a.Address == (Address{}) {
+		v.add("address", FieldErrorRequired, "is required")
+	} else if a.Address != (Address{}) {
+		v.address("address", a.Address)
+	}
+	for i, addr := range a.Addresses {
+		v.address(fmt.Sprintf("addresses[%d]", i), addr)
+	}
+
+	if a.Location != nil && a.Location.CountryOfResidence != "" {
+		v.country("location.country_of_residence", a.Location.CountryOfResidence)
+	}
+
+	country := a.Address.Country
+	for i, n := range a.IDNumbers {
+		v.idNumber(fmt.Sprintf("id_numbers[%d]", i), n, country)
+	}
+
+	return v.errs
+}
+
+type applicantValidator struct {
+	errs []FieldError
+}
+
+func (v *applicantValidator) add(field string, code FieldErrorCode, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *applicantValidator) required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, FieldErrorRequired, "is required")
+		return false
+	}
+	return true
+}
+
+func (v *applicantValidator) date(field, value string) (time.Time, bool) {
+	t, err := time.Parse(DateLayout, value)
+	if err != nil {
+		v.add(field, FieldErrorInvalidFormat, "must be a date formatted as YYYY-MM-DD")
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func (v *applicantValidator) dob(field, value string) {
+	if t, ok := v.date(field, value); ok && t.After(time.Now()) {
+		v.add(field, FieldErrorInvalidFormat, "must not be in the future")
+	}
+}
+
+func (v *applicantValidator) country(field string, value CountryCode) bool {
+	if !value.IsValid() {
+		v.add(field, FieldErrorUnknownValue, "must be an upper-case ISO 3166-1 alpha-3 country code")
+		return false
+	}
+	return true
+}
+
+// state checks the value is a state code exactly as it will be sent.
+func (v *applicantValidator) state(field string, value USStateCode) {
+	if !value.IsValid() {
+		v.add(field, FieldErrorUnknownValue, "must be an upper-case USPS state code")
+	}
+}
+
+func (v *applicantValidator) address(field string, a Address) {
+	v.required(field+".street", a.Street)
+	v.required(field+".town", a.Town)
+	if !v.required(field+".country", string(a.Country)) || !v.country(field+".country", a.Country) {
+		return
+	}
+
+	hasPostcode := strings.TrimSpace(a.Postcode) != ""
+	if !hasPostcode && !countriesWithoutPostcodes[a.Country] {
+		v.add(field+".postcode", FieldErrorRequired, "is required")
+	}
+	if re, ok := postcodeFormats[a.Country]; ok && hasPostcode && !re.MatchString(strings.ToUpper(strings.TrimSpace(a.Postcode))) {
+		v.add(field+".postcode", FieldErrorInvalidFormat, "is not a valid %s postcode", a.Country)
+	}
+	if a.Country == "USA" && v.required(field+".state", a.State) {
+		v.state(field+".state", USStateCode(a.State))
+	}
+	if a.StartDate != "" {
+		v.date(field+".start_date", a.StartDate)
+	}
+	if a.EndDate != "" {
+		v.date(field+".end_date", a.EndDate)
+	}
+}
+
+func (v *applicantValidator) idNumber(field string, n IDNumber, country CountryCode) {
+	if !idNumberTypes[n.Type] {
+		v.add(field+".type", FieldErrorUnknownValue, "%q is not a supported ID number type", n.Type)
+		return
+	}
+
+	if !v.required(field+".value", n.Value) {
+		return
+	}
+	if re, ok := idNumberFormats[n.Type]; ok && !re.MatchString(strings.TrimSpace(n.Value)) {
+		v.add(field+".value", FieldErrorInvalidFormat, "is not a valid %s", n.Type)
+	}
+
+	if n.StateCode != "" {
+		v.state(field+".state_code", n.StateCode)
+	} else if n.Type == IDNumberTypeDrivingLicense && country == "USA" {
+		v.add(field+".state_code", FieldErrorRequired, "is required for US driving licences")
+	}
+}
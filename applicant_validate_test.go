@@ -0,0 +1,138 @@
+package onfido
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validUSApplicant() Applicant {
+	return Applicant{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		DOB:       "1990-02-28",
+		Address: Address{
+			BuildingNumber: "100",
+			Street:         "Main Street",
+			Town:           "Springfield",
+			State:          "IL",
+			Postcode:       "62701",
+			Country:        "USA",
+		},
+		IDNumbers: []IDNumber{
+			{Type: IDNumberTypeSSN, Value: "123-45-6789"},
+			{Type: IDNumberTypeDrivingLicense, Value: "D1234567", StateCode: "IL"},
+		},
+		Location: &Location{CountryOfResidence: "USA"},
+	}
+}
+
+func fieldErrors(errs []FieldError) map[string]FieldErrorCode {
+	m := make(map[string]FieldErrorCode)
+	for _, e := range errs {
+		m[e.Field] = e.Code
+	}
+	return m
+}
+
+func TestValidateApplicantForReports_Valid(t *testing.T) {
+	errs := ValidateApplicantForReports(validUSApplicant(), []ReportName{ReportNameIdentityEnhanced, ReportNameWatchlistEnhanced})
+	assert.Empty(t, errs)
+}
+
+func TestValidateApplicantForReports_RequiredByReports(t *testing.T) {
+	a := Applicant{FirstName: "Jane", LastName: "Doe"}
+
+	assert.Empty(t, ValidateApplicantForReports(a, []ReportName{ReportNameDocument}))
+	assert.Equal(t, map[string]FieldErrorCode{
+		"dob":     FieldErrorRequired,
+		"address": FieldErrorRequired,
+	}, fieldErrors(ValidateApplicantForReports(a, []ReportName{ReportNameIdentityEnhanced})))
+	assert.Equal(t, map[string]FieldErrorCode{
+		"dob": FieldErrorRequired,
+	}, fieldErrors(ValidateApplicantForReports(a, []ReportName{ReportNameWatchlistEnhanced})))
+}
+
+func TestValidateApplicantForReports_Formats(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(a *Applicant)
+		field  string
+		code   FieldErrorCode
+	}{
+		{"missing first name", func(a *Applicant) { a.FirstName = " " }, "first_name", FieldErrorRequired},
+		{"dob format", func(a *Applicant) { a.DOB = "28/02/1990" }, "dob", FieldErrorInvalidFormat},
+		{"dob in future", func(a *Applicant) { a.DOB = "2999-01-01" }, "dob", FieldErrorInvalidFormat},
+		{"alpha-2 country", func(a *Applicant) { a.Address.Country = "US" }, "address.country", FieldErrorUnknownValue},
+		{"lower case country", func(a *Applicant) { a.Address.Country = "usa" }, "address.country", FieldErrorUnknownValue},
+		{"zip code", func(a *Applicant) { a.Address.Postcode = "6270" }, "address.postcode", FieldErrorInvalidFormat},
+		{"missing state", func(a *Applicant) { a.Address.State = "" }, "address.state", FieldErrorRequired},
+		{"unknown state", func(a *Applicant) { a.Address.State = "XX" }, "address.state", FieldErrorUnknownValue},
+		{"lower case state", func(a *Applicant) { a.Address.State = "il" }, "address.state", FieldErrorUnknownValue},
+		{"missing postcode", func(a *Applicant) { a.Address.Postcode = "" }, "address.postcode", FieldErrorRequired},
+		{"ssn format", func(a *Applicant) { a.IDNumbers[0].Value = "12345678" }, "id_numbers[0].value", FieldErrorInvalidFormat},
+		{"unknown id type", func(a *Applicant) { a.IDNumbers[0].Type = "library_card" }, "id_numbers[0].type", FieldErrorUnknownValue},
+		{"lower case licence state", func(a *Applicant) { a.IDNumbers[1].StateCode = "il" }, "id_numbers[1].state_code", FieldErrorUnknownValue},
+		{"licence state", func(a *Applicant) { a.IDNumbers[1].StateCode = "" }, "id_numbers[1].state_code", FieldErrorRequired},
+		{"residence", func(a *Applicant) { a.Location.CountryOfResidence = "XXX" }, "location.country_of_residence", FieldErrorUnknownValue},
+		{"address history", func(a *Applicant) {
+			a.Addresses = []Address{{Street: "Baker Street", Town: "London", Postcode: "W1U8ED", Country: "GBR", StartDate: "2017"}}
+		}, "addresses[0].start_date", FieldErrorInvalidFormat},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			a := validUSApplicant()
+			tt.modify(&a)
+			errs := ValidateApplicantForReports(a, []ReportName{ReportNameIdentityEnhanced})
+			assert.Equal(t, map[string]FieldErrorCode{tt.field: tt.code}, fieldErrors(errs))
+		})
+	}
+}
+
+func TestValidateApplicantForReports_Postcodes(t *testing.T) {
+	tests := []struct {
+		country  CountryCode
+		postcode string
+		valid    bool
+	}{
+		{"GBR", "W1U 8ED", true},
+		{"GBR", "sw1a1aa", true},
+		{"GBR", "12345", false},
+		{"CAN", "K1A 0B1", true},
+		{"CAN", "K1A", false},
+		{"USA", "62701-1234", true},
+		{"NLD", "1012 JS", true},
+		{"BRA", "anything", true},
+		{"BRA", "", false},
+		{"ARE", "", true},
+		{"HKG", "", true},
+	}
+
+	for _, tt := range tests {
+		a := Applicant{
+			FirstName: "Jane",
+			LastName:  "Doe",
+			Address:   Address{Street: "Street", Town: "Town", State: "NY", Postcode: tt.postcode, Country: tt.country},
+		}
+		errs := ValidateApplicantForReports(a, nil)
+		assert.Equal(t, tt.valid, len(errs) == 0, "%s %s: %v", tt.country, tt.postcode, errs)
+	}
+}
+
+func TestValidateApplicantForReports_IDNumberTypes(t *testing.T) {
+	a := validUSApplicant()
+	a.IDNumbers = []IDNumber{
+		{Type: IDNumberTypePassport, Value: "123456789"},
+		{Type: IDNumberTypeVoterID, Value: "V1"},
+		{Type: IDNumberTypeShareCode, Value: "ABC123DEF"},
+		{Type: IDNumberTypeOther, Value: "X1"},
+	}
+	assert.Empty(t, ValidateApplicantForReports(a, nil))
+}
+
+func TestFieldError_Error(t *testing.T) {
+	e := FieldError{Field: "dob", Code: FieldErrorRequired, Message: "is required"}
+	assert.Equal(t, "dob: is required", e.Error())
+}
@@ -1,5 +1,11 @@
 package onfido
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
 const (
 	BreakdownClear        BreakdownResult = "clear"
 	BreakdownConsider     BreakdownResult = "consider"
@@ -29,3 +35,137 @@ type SubBreakdown struct {
 	Result     *BreakdownSubResult `json:"result"`
 	Properties Properties          `json:"properties"`
 }
+
+// BreakdownNode represents a breakdown or sub-breakdown visited by Breakdowns.Walk.
+type BreakdownNode struct {
+	// Path is the dot separated path to the node, e.g. visual_authenticity.fonts
+	Path string
+	// Result is the node's result, empty if Onfido returned a null result
+	Result string
+	// Properties are the node's properties, only set on sub-breakdowns
+	Properties Properties
+	// Depth is 0 for breakdowns and 1 for sub-breakdowns
+	Depth int
+}
+
+// HasResult reports whether Onfido returned a result for the node.
+func (n BreakdownNode) HasResult() bool {
+	return n.Result != ""
+}
+
+// Failed reports whether the node's result is neither clear nor null.
+func (n BreakdownNode) Failed() bool {
+	return n.HasResult() && n.Result != string(BreakdownClear)
+}
+
+// Walk calls fn for each breakdown followed by its sub-breakdowns, in key
+// order. Walking stops at the first error returned by fn.
+func (b Breakdowns) Walk(fn func(BreakdownNode) error) error {
+	for _, name := range sortedKeys(b) {
+		bd := b[name]
+		node := BreakdownNode{Path: name}
+		if bd.Result != nil {
+			node.Result = string(*bd.Result)
+		}
+		if err := fn(node); err != nil {
+			return err
+		}
+
+		for _, subName := range sortedKeys(bd.SubBreakdowns) {
+			sub := bd.SubBreakdowns[subName]
+			node := BreakdownNode{
+				Path:       name + "." + subName,
+				Properties: sub.Properties,
+				Depth:      1,
+			}
+			if sub.Result != nil {
+				node.Result = string(*sub.Result)
+			}
+			if err := fn(node); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Failures returns the nodes which explain why the report was not clear.
+// Failed sub-breakdowns are returned in place of their parent; a failed
+// breakdown is only returned itself if none of its sub-breakdowns failed.
+func (b Breakdowns) Failures() []BreakdownNode {
+	var failures []BreakdownNode
+	var parent *BreakdownNode
+	explained := false
+
+	flush := func() {
+		if parent != nil && parent.Failed() && !explained {
+			failures = append(failures, *parent)
+		}
+	}
+
+	_ = b.Walk(func(n BreakdownNode) error {
+		if n.Depth == 0 {
+			flush()
+			parent, explained = &n, false
+			return nil
+		}
+		if n.Failed() {
+			failures = append(failures, n)
+			explained = true
+		}
+		return nil
+	})
+	flush()
+
+	return failures
+}
+
+// Summary returns a deterministic, human readable explanation of the
+// breakdowns which were not clear, one per line.
+func (b Breakdowns) Summary() string {
+	failures := b.Failures()
+	if len(failures) == 0 {
+		return "no breakdowns failed"
+	}
+
+	var sb strings.Builder
+	for i, f := range failures {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(f.Path)
+		sb.WriteString(": ")
+		sb.WriteString(f.Result)
+		if len(f.Properties) > 0 {
+			sb.WriteString(" (")
+			for j, k := range sortedKeys(f.Properties) {
+				if j > 0 {
+					sb.WriteString(", ")
+				}
+				fmt.Fprintf(&sb, "%s=%v", k, f.Properties[k])
+			}
+			sb.WriteString(")")
+		}
+	}
+	return sb.String()
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case Breakdowns:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case SubBreakdowns:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case Properties:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
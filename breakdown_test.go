@@ -0,0 +1,82 @@
+package onfido
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testBreakdowns(t *testing.T) Breakdowns {
+	var b Breakdowns
+	err := json.Unmarshal([]byte(`{
+		"visual_authenticity": {
+			"result": "consider",
+			"breakdown": {
+				"template": {"result": "clear", "properties": {}},
+				"fonts": {"result": "consider", "properties": {"confidence": "low"}},
+				"other": {"result": null, "properties": {}}
+			}
+		},
+		"police_record": {"result": "consider"},
+		"image_integrity": {
+			"result": "clear",
+			"breakdown": {"image_quality": {"result": "clear"}}
+		},
+		"some_future_check": {"result": null}
+	}`), &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBreakdowns_Walk(t *testing.T) {
+	var paths []string
+	err := testBreakdowns(t).Walk(func(n BreakdownNode) error {
+		paths = append(paths, n.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{
+		"image_integrity",
+		"image_integrity.image_quality",
+		"police_record",
+		"some_future_check",
+		"visual_authenticity",
+		"visual_authenticity.fonts",
+		"visual_authenticity.other",
+		"visual_authenticity.template",
+	}, paths)
+}
+
+func TestBreakdowns_Walk_StopsOnError(t *testing.T) {
+	stop := errors.New("stop")
+	visited := 0
+	err := testBreakdowns(t).Walk(func(n BreakdownNode) error {
+		visited++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, visited)
+}
+
+func TestBreakdowns_Failures(t *testing.T) {
+	failures := testBreakdowns(t).Failures()
+	if assert.Len(t, failures, 2) {
+		assert.Equal(t, "police_record", failures[0].Path)
+		assert.Equal(t, "consider", failures[0].Result)
+		assert.Equal(t, "visual_authenticity.fonts", failures[1].Path)
+		assert.Equal(t, Properties{"confidence": "low"}, failures[1].Properties)
+	}
+}
+
+func TestBreakdowns_Summary(t *testing.T) {
+	assert.Equal(t,
+		"police_record: consider\nvisual_authenticity.fonts: consider (confidence=low)",
+		testBreakdowns(t).Summary())
+	assert.Equal(t, "no breakdowns failed", Breakdowns(nil).Summary())
+}
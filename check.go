@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"time"
 )
 
@@ -159,6 +161,32 @@ func (c *client) GetCheckExpanded(ctx context.Context, id string) (*Check, error
 	return &check, nil
 }
 
+// CheckDownload represents a downloaded check PDF
+type CheckDownload struct {
+	// Data is the binary data of the check's PDF
+	Data []byte
+}
+
+// DownloadCheck returns the PDF of a completed check.
+// see https://documentation.onfido.com/#download-check
+func (c *client) DownloadCheck(ctx context.Context, id string) (*CheckDownload, error) {
+	var resp bytes.Buffer
+	if err := c.downloadCheckTo(ctx, id, &resp); err != nil {
+		return nil, err
+	}
+	return &CheckDownload{
+		Data: resp.Bytes(),
+	}, nil
+}
+
+// downloadCheckTo writes the check's file to w.
+func (c *client) downloadCheckTo(ctx context.Context, id string, w io.Writer) error {
+	if err := c.download(ctx, "/checks/"+id+"/download", w); err != nil {
+		return fmt.Errorf("failed to download check: %w", err)
+	}
+	return nil
+}
+
 // ResumeCheck resumes a paused check by its ID.
 // see https://documentation.onfido.com/?shell#resume-check
 func (c *client) ResumeCheck(ctx context.Context, id string) (*Check, error) {
@@ -11,6 +11,32 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func TestDownloadCheck(t *testing.T) {
+	mockCheckID := "ce62d838-56f8-4ea5-98be-e7166d1dc33d"
+	m := mux.NewRouter()
+	m.HandleFunc("/checks/{checkId}/download", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		assert.Equal(t, mockCheckID, vars["checkId"])
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.WriteHeader(http.StatusOK)
+		_, wErr := w.Write([]byte("this is a pdf"))
+		assert.NoError(t, wErr)
+	}).Methods("GET")
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	checkDownload, err := client.DownloadCheck(context.Background(), mockCheckID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []byte("this is a pdf"), checkDownload.Data)
+}
+
 func TestCreateCheck_NonOKResponse(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusForbidden)
@@ -269,6 +295,7 @@ func TestGetCheckExpanded_HasReports(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	expectedReport1.Raw = expectedReport1Json
 
 	// Expected Report 2
 	expectedReport2 := Report{
@@ -284,6 +311,7 @@ func TestGetCheckExpanded_HasReports(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	expectedReport2.Raw = expectedReport2Json
 
 	m := mux.NewRouter()
 	// Return the requested Report
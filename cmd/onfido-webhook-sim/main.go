@@ -0,0 +1,60 @@
+// Command onfido-webhook-sim sends signed Onfido webhook events to a local endpoint.
+//
+//	onfido-webhook-sim -url http://localhost:8080/webhook/onfido -event check.completed -id <check id>
+//
+// The webhook token is read from -token or the ONFIDO_WEBHOOK_TOKEN environment variable.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/esqimo/go-onfido"
+	"github.com/esqimo/go-onfido/webhooktest"
+)
+
+func main() {
+	url := flag.String("url", "http://localhost:8080/webhook/onfido", "webhook endpoint to send events to")
+	token := flag.String("token", os.Getenv(onfido.WebhookTokenEnv), "webhook token used to sign events")
+	event := flag.String("event", string(onfido.WebhookEventCheckCompleted), "webhook event to send")
+	id := flag.String("id", "", "id of the event's object")
+	duplicates := flag.Int("duplicates", 0, "number of additional times to send the event")
+	delay := flag.Duration("delay", 0, "delay before each delivery")
+	corrupt := flag.Bool("corrupt", false, "send an invalid signature")
+	list := flag.Bool("list", false, "list known events and exit")
+	flag.Parse()
+
+	if *list {
+		for _, e := range onfido.WebhookEvents {
+			fmt.Println(e)
+		}
+		return
+	}
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "webhook token required, set -token or "+onfido.WebhookTokenEnv)
+		os.Exit(2)
+	}
+	if *id == "" {
+		*id = fmt.Sprintf("sim-%d", time.Now().UnixNano())
+	}
+
+	s := &webhooktest.Sender{
+		Token:            *token,
+		URL:              *url,
+		Duplicates:       *duplicates,
+		Delay:            *delay,
+		CorruptSignature: *corrupt,
+	}
+	codes, err := s.Send(context.Background(), webhooktest.NewEvent(onfido.WebhookEvent(*event), *id))
+	for i, code := range codes {
+		fmt.Printf("delivery %d: %d %s\n", i+1, code, http.StatusText(code))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,477 @@
+package onfido
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Reference data errors
+var (
+	ErrUnknownCountryCode = errors.New("unknown ISO 3166-1 alpha-3 country code")
+	ErrUnknownUSStateCode = errors.New("unknown US state code")
+)
+
+// CountryCode represents an ISO 3166-1 alpha-3 country code, e.g. GBR.
+// Codes missing from the reference table are encoded and decoded as they
+// are, so responses containing them can be sent back to Onfido; Validate
+// catches mistakes such as alpha-2 codes in new data.
+type CountryCode string
+
+type countryInfo struct {
+	alpha2 string
+	name   string
+}
+
+// countries is the ISO 3166-1 reference table, keyed by alpha-3 code. It
+// also includes XKX for Kosovo, which Onfido uses although it is not
+// assigned by ISO.
+var countries = map[CountryCode]countryInfo{
+	"ABW": {"AW", "Aruba"},
+	"AFG": {"AF", "Afghanistan"},
+	"AGO": {"AO", "Angola"},
+	"AIA": {"AI", "Anguilla"},
+	"ALA": {"AX", "Åland Islands"},
+	"ALB": {"AL", "Albania"},
+	"AND": {"AD", "Andorra"},
+	"ARE": {"AE", "United Arab Emirates"},
+	"ARG": {"AR", "Argentina"},
+	"ARM": {"AM", "Armenia"},
+	"ASM": {"AS", "American Samoa"},
+	"ATA": {"AQ", "Antarctica"},
+	"ATF": {"TF", "French Southern Territories"},
+	"ATG": {"AG", "Antigua and Barbuda"},
+	"AUS": {"AU", "Australia"},
+	"AUT": {"AT", "Austria"},
+	"AZE": {"AZ", "Azerbaijan"},
+	"BDI": {"BI", "Burundi"},
+	"BEL": {"BE", "Belgium"},
+	"BEN": {"BJ", "Benin"},
+	"BES": {"BQ", "Bonaire, Sint Eustatius and Saba"},
+	"BFA": {"BF", "Burkina Faso"},
+	"BGD": {"BD", "Bangladesh"},
+	"BGR": {"BG", "Bulgaria"},
+	"BHR": {"BH", "Bahrain"},
+	"BHS": {"BS", "Bahamas"},
+	"BIH": {"BA", "Bosnia and Herzegovina"},
+	"BLM": {"BL", "Saint Barthélemy"},
+	"BLR": {"BY", "Belarus"},
+	"BLZ": {"BZ", "Belize"},
+	"BMU": {"BM", "Bermuda"},
+	"BOL": {"BO", "Bolivia"},
+	"BRA": {"BR", "Brazil"},
+	"BRB": {"BB", "Barbados"},
+	"BRN": {"BN", "Brunei Darussalam"},
+	"BTN": {"BT", "Bhutan"},
+	"BVT": {"BV", "Bouvet Island"},
+	"BWA": {"BW", "Botswana"},
+	"CAF": {"CF", "Central African Republic"},
+	"CAN": {"CA", "Canada"},
+	"CCK": {"CC", "Cocos (Keeling) Islands"},
+	"CHE": {"CH", "Switzerland"},
+	"CHL": {"CL", "Chile"},
+	"CHN": {"CN", "China"},
+	"CIV": {"CI", "Côte d'Ivoire"},
+	"CMR": {"CM", "Cameroon"},
+	"COD": {"CD", "Congo, Democratic Republic of the"},
+	"COG": {"CG", "Congo"},
+	"COK": {"CK", "Cook Islands"},
+	"COL": {"CO", "Colombia"},
+	"COM": {"KM", "Comoros"},
+	"CPV": {"CV", "Cabo Verde"},
+	"CRI": {"CR", "Costa Rica"},
+	"CUB": {"CU", "Cuba"},
+	"CUW": {"CW", "Curaçao"},
+	"CXR": {"CX", "Christmas Island"},
+	"CYM": {"KY", "Cayman Islands"},
+	"CYP": {"CY", "Cyprus"},
+	"CZE": {"CZ", "Czechia"},
+	"DEU": {"DE", "Germany"},
+	"DJI": {"DJ", "Djibouti"},
+	"DMA": {"DM", "Dominica"},
+	"DNK": {"DK", "Denmark"},
+	"DOM": {"DO", "Dominican Republic"},
+	"DZA": {"DZ", "Algeria"},
+	"ECU": {"EC", "Ecuador"},
+	"EGY": {"EG", "Egypt"},
+	"ERI": {"ER", "Eritrea"},
+	"ESH": {"EH", "Western Sahara"},
+	"ESP": {"ES", "Spain"},
+	"EST": {"EE", "Estonia"},
+	"ETH": {"ET", "Ethiopia"},
+	"FIN": {"FI", "Finland"},
+	"FJI": {"FJ", "Fiji"},
+	"FLK": {"FK", "Falkland Islands (Malvinas)"},
+	"FRA": {"FR", "France"},
+	"FRO": {"FO", "Faroe Islands"},
+	"FSM": {"FM", "Micronesia, Federated States of"},
+	"GAB": {"GA", "Gabon"},
+	"GBR": {"GB", "United Kingdom"},
+	"GEO": {"GE", "Georgia"},
+	"GGY": {"GG", "Guernsey"},
+	"GHA": {"GH", "Ghana"},
+	"GIB": {"GI", "Gibraltar"},
+	"GIN": {"GN", "Guinea"},
+	"GLP": {"GP", "Guadeloupe"},
+	"GMB": {"GM", "Gambia"},
+	"GNB": {"GW", "Guinea-Bissau"},
+	"GNQ": {"GQ", "Equatorial Guinea"},
+	"GRC": {"GR", "Greece"},
+	"GRD": {"GD", "Grenada"},
+	"GRL": {"GL", "Greenland"},
+	"GTM": {"GT", "Guatemala"},
+	"GUF": {"GF", "French Guiana"},
+	"GUM": {"GU", "Guam"},
+	"GUY": {"GY", "Guyana"},
+	"HKG": {"HK", "Hong Kong"},
+	"HMD": {"HM", "Heard Island and McDonald Islands"},
+	"HND": {"HN", "Honduras"},
+	"HRV": {"HR", "Croatia"},
+	"HTI": {"HT", "Haiti"},
+	"HUN": {"HU", "Hungary"},
+	"IDN": {"ID", "Indonesia"},
+	"IMN": {"IM", "Isle of Man"},
+	"IND": {"IN", "India"},
+	"IOT": {"IO", "British Indian Ocean Territory"},
+	"IRL": {"IE", "Ireland"},
+	"IRN": {"IR", "Iran"},
+	"IRQ": {"IQ", "Iraq"},
+	"ISL": {"IS", "Iceland"},
+	"ISR": {"IL", "Israel"},
+	"ITA": {"IT", "Italy"},
+	"JAM": {"JM", "Jamaica"},
+	"JEY": {"JE", "Jersey"},
+	"JOR": {"JO", "Jordan"},
+	"JPN": {"JP", "Japan"},
+	"KAZ": {"KZ", "Kazakhstan"},
+	"KEN": {"KE", "Kenya"},
+	"KGZ": {"KG", "Kyrgyzstan"},
+	"KHM": {"KH", "Cambodia"},
+	"KIR": {"KI", "Kiribati"},
+	"KNA": {"KN", "Saint Kitts and Nevis"},
+	"KOR": {"KR", "Korea, Republic of"},
+	"KWT": {"KW", "Kuwait"},
+	"LAO": {"LA", "Lao People's Democratic Republic"},
+	"LBN": {"LB", "Lebanon"},
+	"LBR": {"LR", "Liberia"},
+	"LBY": {"LY", "Libya"},
+	"LCA": {"LC", "Saint Lucia"},
+	"LIE": {"LI", "Liechtenstein"},
+	"LKA": {"LK", "Sri Lanka"},
+	"LSO": {"LS", "Lesotho"},
+	"LTU": {"LT", "Lithuania"},
+	"LUX": {"LU", "Luxembourg"},
+	"LVA": {"LV", "Latvia"},
+	"MAC": {"MO", "Macao"},
+	"MAF": {"MF", "Saint Martin (French part)"},
+	"MAR": {"MA", "Morocco"},
+	"MCO": {"MC", "Monaco"},
+	"MDA": {"MD", "Moldova"},
+	"MDG": {"MG", "Madagascar"},
+	"MDV": {"MV", "Maldives"},
+	"MEX": {"MX", "Mexico"},
+	"MHL": {"MH", "Marshall Islands"},
+	"MKD": {"MK", "North Macedonia"},
+	"MLI": {"ML", "Mali"},
+	"MLT": {"MT", "Malta"},
+	"MMR": {"MM", "Myanmar"},
+	"MNE": {"ME", "Montenegro"},
+	"MNG": {"MN", "Mongolia"},
+	"MNP": {"MP", "Northern Mariana Islands"},
+	"MOZ": {"MZ", "Mozambique"},
+	"MRT": {"MR", "Mauritania"},
+	"MSR": {"MS", "Montserrat"},
+	"MTQ": {"MQ", "Martinique"},
+	"MUS": {"MU", "Mauritius"},
+	"MWI": {"MW", "Malawi"},
+	"MYS": {"MY", "Malaysia"},
+	"MYT": {"YT", "Mayotte"},
+	"NAM": {"NA", "Namibia"},
+	"NCL": {"NC", "New Caledonia"},
+	"NER": {"NE", "Niger"},
+	"NFK": {"NF", "Norfolk Island"},
+	"NGA": {"NG", "Nigeria"},
+	"NIC": {"NI", "Nicaragua"},
+	"NIU": {"NU", "Niue"},
+	"NLD": {"NL", "Netherlands"},
+	"NOR": {"NO", "Norway"},
+	"NPL": {"NP", "Nepal"},
+	"NRU": {"NR", "Nauru"},
+	"NZL": {"NZ", "New Zealand"},
+	"OMN": {"OM", "Oman"},
+	"PAK": {"PK", "Pakistan"},
+	"PAN": {"PA", "Panama"},
+	"PCN": {"PN", "Pitcairn"},
+	"PER": {"PE", "Peru"},
+	"PHL": {"PH", "Philippines"},
+	"PLW": {"PW", "Palau"},
+	"PNG": {"PG", "Papua New Guinea"},
+	"POL": {"PL", "Poland"},
+	"PRI": {"PR", "Puerto Rico"},
+	"PRK": {"KP", "Korea, Democratic People's Republic of"},
+	"PRT": {"PT", "Portugal"},
+	"PRY": {"PY", "Paraguay"},
+	"PSE": {"PS", "Palestine, State of"},
+	"PYF": {"PF", "French Polynesia"},
+	"QAT": {"QA", "Qatar"},
+	"REU": {"RE", "Réunion"},
+	"ROU": {"RO", "Romania"},
+	"RUS": {"RU", "Russian Federation"},
+	"RWA": {"RW", "Rwanda"},
+	"SAU": {"SA", "Saudi Arabia"},
+	"SDN": {"SD", "Sudan"},
+	"SEN": {"SN", "Senegal"},
+	"SGP": {"SG", "Singapore"},
+	"SGS": {"GS", "South Georgia and the South Sandwich Islands"},
+	"SHN": {"SH", "Saint Helena, Ascension and Tristan da Cunha"},
+	"SJM": {"SJ", "Svalbard and Jan Mayen"},
+	"SLB": {"SB", "Solomon Islands"},
+	"SLE": {"SL", "Sierra Leone"},
+	"SLV": {"SV", "El Salvador"},
+	"SMR": {"SM", "San Marino"},
+	"SOM": {"SO", "Somalia"},
+	"SPM": {"PM", "Saint Pierre and Miquelon"},
+	"SRB": {"RS", "Serbia"},
+	"SSD": {"SS", "South Sudan"},
+	"STP": {"ST", "Sao Tome and Principe"},
+	"SUR": {"SR", "Suriname"},
+	"SVK": {"SK", "Slovakia"},
+	"SVN": {"SI", "Slovenia"},
+	"SWE": {"SE", "Sweden"},
+	"SWZ": {"SZ", "Eswatini"},
+	"SXM": {"SX", "Sint Maarten (Dutch part)"},
+	"SYC": {"SC", "Seychelles"},
+	"SYR": {"SY", "Syrian Arab Republic"},
+	"TCA": {"TC", "Turks and Caicos Islands"},
+	"TCD": {"TD", "Chad"},
+	"TGO": {"TG", "Togo"},
+	"THA": {"TH", "Thailand"},
+	"TJK": {"TJ", "Tajikistan"},
+	"TKL": {"TK", "Tokelau"},
+	"TKM": {"TM", "Turkmenistan"},
+	"TLS": {"TL", "Timor-Leste"},
+	"TON": {"TO", "Tonga"},
+	"TTO": {"TT", "Trinidad and Tobago"},
+	"TUN": {"TN", "Tunisia"},
+	"TUR": {"TR", "Turkey"},
+	"TUV": {"TV", "Tuvalu"},
+	"TWN": {"TW", "Taiwan"},
+	"TZA": {"TZ", "Tanzania"},
+	"UGA": {"UG", "Uganda"},
+	"UKR": {"UA", "Ukraine"},
+	"UMI": {"UM", "United States Minor Outlying Islands"},
+	"URY": {"UY", "Uruguay"},
+	"USA": {"US", "United States of America"},
+	"UZB": {"UZ", "Uzbekistan"},
+	"VAT": {"VA", "Holy See"},
+	"VCT": {"VC", "Saint Vincent and the Grenadines"},
+	"VEN": {"VE", "Venezuela"},
+	"VGB": {"VG", "Virgin Islands (British)"},
+	"VIR": {"VI", "Virgin Islands (U.S.)"},
+	"VNM": {"VN", "Viet Nam"},
+	"VUT": {"VU", "Vanuatu"},
+	"WLF": {"WF", "Wallis and Futuna"},
+	"WSM": {"WS", "Samoa"},
+	"YEM": {"YE", "Yemen"},
+	"ZAF": {"ZA", "South Africa"},
+	"ZMB": {"ZM", "Zambia"},
+	"ZWE": {"ZW", "Zimbabwe"},
+	"XKX": {"XK", "Kosovo"},
+}
+
+// countryAliases are common non-ISO spellings of country codes.
+var countryAliases = map[string]CountryCode{
+	"UK": "GBR",
+}
+
+var (
+	countriesByAlpha2 = make(map[string]CountryCode, len(countries))
+	countriesByName   = make(map[string]CountryCode, len(countries))
+)
+
+func init() {
+	for code, c := range countries {
+		countriesByAlpha2[c.alpha2] = code
+		countriesByName[strings.ToLower(c.name)] = code
+	}
+}
+
+// ParseCountryCode converts an alpha-3 code, alpha-2 code or English
+// country name, in any case, to a CountryCode.
+func ParseCountryCode(s string) (CountryCode, error) {
+	s = strings.TrimSpace(s)
+	upper := strings.ToUpper(s)
+	if _, ok := countries[CountryCode(upper)]; ok {
+		return CountryCode(upper), nil
+	}
+	if code, ok := countriesByAlpha2[upper]; ok {
+		return code, nil
+	}
+	if code, ok := countryAliases[upper]; ok {
+		return code, nil
+	}
+	if code, ok := countriesByName[strings.ToLower(s)]; ok {
+		return code, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownCountryCode, s)
+}
+
+// IsValid reports whether the code is in the reference table.
+func (c CountryCode) IsValid() bool {
+	_, ok := countries[c]
+	return ok
+}
+
+// Alpha2 returns the ISO 3166-1 alpha-2 code of the country, or "" if the code is unknown.
+func (c CountryCode) Alpha2() string {
+	return countries[c].alpha2
+}
+
+// Name returns the English name of the country, or "" if the code is unknown.
+func (c CountryCode) Name() string {
+	return countries[c].name
+}
+
+// Validate returns an error wrapping ErrUnknownCountryCode if the code is
+// set and unknown.
+func (c CountryCode) Validate() error {
+	if c != "" && !c.IsValid() {
+		return fmt.Errorf("%w: %q", ErrUnknownCountryCode, string(c))
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes the code, normalising it to alpha-3 if it is known.
+func (c *CountryCode) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if code, err := ParseCountryCode(s); err == nil {
+		*c = code
+	} else {
+		*c = CountryCode(s)
+	}
+	return nil
+}
+
+// USStateCode represents the USPS code of a US state, district or
+// territory, e.g. NY. Like CountryCode, unknown codes are encoded and
+// decoded as they are and reported by Validate.
+type USStateCode string
+
+// usStates is the US state reference table, keyed by USPS code.
+var usStates = map[USStateCode]string{
+	"AL": "Alabama",
+	"AK": "Alaska",
+	"AZ": "Arizona",
+	"AR": "Arkansas",
+	"CA": "California",
+	"CO": "Colorado",
+	"CT": "Connecticut",
+	"DE": "Delaware",
+	"DC": "District of Columbia",
+	"FL": "Florida",
+	"GA": "Georgia",
+	"HI": "Hawaii",
+	"ID": "Idaho",
+	"IL": "Illinois",
+	"IN": "Indiana",
+	"IA": "Iowa",
+	"KS": "Kansas",
+	"KY": "Kentucky",
+	"LA": "Louisiana",
+	"ME": "Maine",
+	"MD": "Maryland",
+	"MA": "Massachusetts",
+	"MI": "Michigan",
+	"MN": "Minnesota",
+	"MS": "Mississippi",
+	"MO": "Missouri",
+	"MT": "Montana",
+	"NE": "Nebraska",
+	"NV": "Nevada",
+	"NH": "New Hampshire",
+	"NJ": "New Jersey",
+	"NM": "New Mexico",
+	"NY": "New York",
+	"NC": "North Carolina",
+	"ND": "North Dakota",
+	"OH": "Ohio",
+	"OK": "Oklahoma",
+	"OR": "Oregon",
+	"PA": "Pennsylvania",
+	"RI": "Rhode Island",
+	"SC": "South Carolina",
+	"SD": "South Dakota",
+	"TN": "Tennessee",
+	"TX": "Texas",
+	"UT": "Utah",
+	"VT": "Vermont",
+	"VA": "Virginia",
+	"WA": "Washington",
+	"WV": "West Virginia",
+	"WI": "Wisconsin",
+	"WY": "Wyoming",
+	"AS": "American Samoa",
+	"GU": "Guam",
+	"MP": "Northern Mariana Islands",
+	"PR": "Puerto Rico",
+	"VI": "U.S. Virgin Islands",
+	"UM": "U.S. Minor Outlying Islands",
+}
+
+var usStatesByName = make(map[string]USStateCode, len(usStates))
+
+func init() {
+	for code, name := range usStates {
+		usStatesByName[strings.ToLower(name)] = code
+	}
+}
+
+// ParseUSStateCode converts a USPS code or state name, in any case, to a USStateCode.
+func ParseUSStateCode(s string) (USStateCode, error) {
+	s = strings.TrimSpace(s)
+	if code := USStateCode(strings.ToUpper(s)); code.IsValid() {
+		return code, nil
+	}
+	if code, ok := usStatesByName[strings.ToLower(s)]; ok {
+		return code, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownUSStateCode, s)
+}
+
+// IsValid reports whether the code is in the reference table.
+func (c USStateCode) IsValid() bool {
+	_, ok := usStates[c]
+	return ok
+}
+
+// Name returns the name of the state, or "" if the code is unknown.
+func (c USStateCode) Name() string {
+	return usStates[c]
+}
+
+// Validate returns an error wrapping ErrUnknownUSStateCode if the code is
+// set and unknown.
+func (c USStateCode) Validate() error {
+	if c != "" && !c.IsValid() {
+		return fmt.Errorf("%w: %q", ErrUnknownUSStateCode, string(c))
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes the code, normalising it if it is known.
+func (c *USStateCode) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if code, err := ParseUSStateCode(s); err == nil {
+		*c = code
+	} else {
+		*c = USStateCode(s)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package onfido
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCountryCode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want CountryCode
+	}{
+		{"GBR", "GBR"},
+		{"gbr", "GBR"},
+		{"GB", "GBR"},
+		{"uk", "GBR"},
+		{" us ", "USA"},
+		{"United Kingdom", "GBR"},
+		{"côte d'ivoire", "CIV"},
+		{"XKX", "XKX"},
+	}
+	for _, tt := range tests {
+		got, err := ParseCountryCode(tt.in)
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	_, err := ParseCountryCode("Atlantis")
+	assert.True(t, errors.Is(err, ErrUnknownCountryCode))
+}
+
+func TestCountryCode_ReferenceTable(t *testing.T) {
+	// 249 ISO 3166-1 countries plus Kosovo
+	assert.Len(t, countries, 250)
+	assert.Len(t, countriesByAlpha2, len(countries))
+	for code, c := range countries {
+		assert.Len(t, string(code), 3)
+		assert.Equal(t, strings.ToUpper(string(code)), string(code))
+		assert.Len(t, c.alpha2, 2)
+		assert.NotEmpty(t, c.name)
+	}
+
+	assert.Equal(t, "DE", CountryCode("DEU").Alpha2())
+	assert.Equal(t, "Germany", CountryCode("DEU").Name())
+	assert.False(t, CountryCode("DE").IsValid())
+}
+
+func TestCountryCode_JSON(t *testing.T) {
+	data, err := json.Marshal(Address{Country: "GBR"})
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"country":"GBR"`)
+
+	// Decoding normalises known codes and keeps unknown ones, which are
+	// encoded again as they are
+	var a Address
+	assert.NoError(t, json.Unmarshal([]byte(`{"country": "gb"}`), &a))
+	assert.Equal(t, CountryCode("GBR"), a.Country)
+	assert.NoError(t, json.Unmarshal([]byte(`{"country": "ZZZ"}`), &a))
+	assert.Equal(t, CountryCode("ZZZ"), a.Country)
+	data, err = json.Marshal(a)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"country":"ZZZ"`)
+}
+
+func TestCountryCode_Validate(t *testing.T) {
+	assert.NoError(t, CountryCode("").Validate())
+	assert.NoError(t, CountryCode("GBR").Validate())
+	err := CountryCode("GB").Validate()
+	assert.True(t, errors.Is(err, ErrUnknownCountryCode), "%v", err)
+}
+
+func TestUSStateCode(t *testing.T) {
+	assert.Len(t, usStates, 57)
+
+	code, err := ParseUSStateCode("new york")
+	assert.NoError(t, err)
+	assert.Equal(t, USStateCode("NY"), code)
+	code, err = ParseUSStateCode("ca")
+	assert.NoError(t, err)
+	assert.Equal(t, USStateCode("CA"), code)
+	assert.Equal(t, "California", code.Name())
+
+	_, err = ParseUSStateCode("ZZ")
+	assert.True(t, errors.Is(err, ErrUnknownUSStateCode))
+
+	assert.NoError(t, USStateCode("NY").Validate())
+	err = USStateCode("New York").Validate()
+	assert.True(t, errors.Is(err, ErrUnknownUSStateCode), "%v", err)
+
+	data, err := json.Marshal(IDNumber{Type: IDNumberTypeDrivingLicense, Value: "D1", StateCode: "New York"})
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"state_code":"New York"`)
+	data, err = json.Marshal(IDNumber{Type: IDNumberTypeSSN, Value: "123-45-6789"})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "state_code")
+}
+
+func TestCreateApplicant_RejectsUnknownCountry(t *testing.T) {
+	client := NewClient("123").(*client)
+	client.endpoint = "http://127.0.0.1:0"
+
+	_, err := client.CreateApplicant(context.Background(), Applicant{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Location:  &Location{CountryOfResidence: "US"},
+	})
+	assert.True(t, errors.Is(err, ErrUnknownCountryCode), "%v", err)
+
+	_, err = client.CreateApplicant(context.Background(), Applicant{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		IDNumbers: []IDNumber{{Type: IDNumberTypeDrivingLicense, Value: "D1", StateCode: "ny"}},
+	})
+	assert.True(t, errors.Is(err, ErrUnknownUSStateCode), "%v", err)
+}
+
+func TestUpdateApplicant_KeepsUnknownCountry(t *testing.T) {
+	m := mux.NewRouter()
+	m.HandleFunc("/applicants/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var a Applicant
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&a))
+		assert.Equal(t, CountryCode("ZZZ"), a.Address.Country)
+
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(a))
+	}).Methods("PUT")
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	// An applicant fetched with a code missing from the reference table
+	// can be sent back
+	var a Applicant
+	assert.NoError(t, json.Unmarshal([]byte(`{"id": "app-1", "first_name": "Jane", "address": {"country": "ZZZ"}}`), &a))
+	a.FirstName = "Janet"
+	_, err := client.UpdateApplicant(context.Background(), a)
+	assert.NoError(t, err)
+}
+
+func TestUploadDocument_RejectsUnknownIssuingCountry(t *testing.T) {
+	client := NewClient("123").(*client)
+	client.endpoint = "http://127.0.0.1:0"
+
+	_, err := client.UploadDocument(context.Background(), DocumentRequest{IssuingCountry: "GB"})
+	assert.True(t, errors.Is(err, ErrUnknownCountryCode), "%v", err)
+}
@@ -0,0 +1,122 @@
+package onfido
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// DateLayout is the layout Onfido uses for calendar dates.
+const DateLayout = "2006-01-02"
+
+// partialDate matches the partial dates Onfido returns when only part of a
+// date could be read, e.g. 1990-00-00, 1990-05 or 1990.
+var partialDate = regexp.MustCompile(`^(\d{4})(?:-(\d{2})(?:-(\d{2}))?)?$`)
+
+// Date represents a calendar date without a time or location, such as a
+// date of birth or document expiry date. The zero value represents an
+// unknown date and is encoded as null. A partial date has a zero Month or
+// Day where that part of the date is unknown.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// ParseDate parses a date in the YYYY-MM-DD format. Partial dates with
+// unknown parts set to 00 or left off, such as 1990-00-00 or 1990, are
+// parsed with those parts zero.
+func ParseDate(s string) (Date, error) {
+	if t, err := time.Parse(DateLayout, s); err == nil {
+		return DateOf(t), nil
+	}
+
+	m := partialDate.FindStringSubmatch(s)
+	if m == nil {
+		return Date{}, fmt.Errorf("invalid date %q", s)
+	}
+	var d Date
+	d.Year, _ = strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	d.Month = time.Month(month)
+	d.Day, _ = strconv.Atoi(m[3])
+	if d.Month > time.December || d.Day > 31 || (d.Month == 0 && d.Day != 0) ||
+		(d.Day != 0 && d.In(time.UTC).Day() != d.Day) {
+		return Date{}, fmt.Errorf("invalid date %q", s)
+	}
+	return d, nil
+}
+
+// DateOf returns the date on which the given time falls.
+func DateOf(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{Year: y, Month: m, Day: d}
+}
+
+// IsZero reports whether the date is unknown.
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+// IsPartial reports whether the date is known but its month or day isn't.
+func (d Date) IsPartial() bool {
+	return !d.IsZero() && (d.Month == 0 || d.Day == 0)
+}
+
+// String returns the date in the YYYY-MM-DD format, with unknown parts as 00.
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// Before reports whether d is before d2.
+func (d Date) Before(d2 Date) bool {
+	if d.Year != d2.Year {
+		return d.Year < d2.Year
+	}
+	if d.Month != d2.Month {
+		return d.Month < d2.Month
+	}
+	return d.Day < d2.Day
+}
+
+// In returns the time at midnight on the date in the given location. The
+// unknown parts of a partial date are taken as the first month or day.
+func (d Date) In(loc *time.Location) time.Time {
+	month, day := d.Month, d.Day
+	if month == 0 {
+		month = time.January
+	}
+	if day == 0 {
+		day = 1
+	}
+	return time.Date(d.Year, month, day, 0, 0, 0, 0, loc)
+}
+
+// MarshalJSON encodes the date as a YYYY-MM-DD string, or null if unknown.
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes a YYYY-MM-DD string, or a partial date as accepted
+// by ParseDate. Null and empty strings decode to the zero date.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == nil || *s == "" {
+		*d = Date{}
+		return nil
+	}
+	v, err := ParseDate(*s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
@@ -0,0 +1,85 @@
+package onfido
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDate_JSON(t *testing.T) {
+	var v struct {
+		A Date `json:"a"`
+		B Date `json:"b"`
+		C Date `json:"c"`
+	}
+	if err := json.Unmarshal([]byte(`{"a": "1990-02-28", "b": null, "c": ""}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Date{Year: 1990, Month: time.February, Day: 28}, v.A)
+	assert.True(t, v.B.IsZero())
+	assert.True(t, v.C.IsZero())
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, `{"a": "1990-02-28", "b": null, "c": null}`, string(out))
+}
+
+func TestDate_InvalidJSON(t *testing.T) {
+	var d Date
+	assert.Error(t, json.Unmarshal([]byte(`"28/02/1990"`), &d))
+}
+
+func TestDate_Before(t *testing.T) {
+	a := Date{Year: 2020, Month: time.January, Day: 31}
+	b := Date{Year: 2020, Month: time.February, Day: 1}
+	assert.True(t, a.Before(b))
+	assert.False(t, b.Before(a))
+	assert.False(t, a.Before(a))
+}
+
+func TestParseDate_Partial(t *testing.T) {
+	for s, want := range map[string]Date{
+		"1990-00-00": {Year: 1990},
+		"1990-05-00": {Year: 1990, Month: time.May},
+		"1990-05":    {Year: 1990, Month: time.May},
+		"1990":       {Year: 1990},
+	} {
+		d, err := ParseDate(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, want, d, s)
+			assert.True(t, d.IsPartial(), s)
+		}
+	}
+
+	for _, s := range []string{"1990-13-00", "1990-00-05", "1990-02-30", "90", "1990-5-1"} {
+		_, err := ParseDate(s)
+		assert.Error(t, err, s)
+	}
+
+	d, err := ParseDate("1990-02-28")
+	assert.NoError(t, err)
+	assert.False(t, d.IsPartial())
+}
+
+func TestDate_PartialJSON(t *testing.T) {
+	var r Report
+	err := json.Unmarshal([]byte(`{"name": "document", "properties": {"date_of_birth": "1990-00-00", "date_of_expiry": "2030"}}`), &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := r.AsDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Date{Year: 1990}, doc.Properties.DateOfBirth)
+	assert.Equal(t, Date{Year: 2030}, doc.Properties.DateOfExpiry)
+
+	out, err := json.Marshal(doc.Properties.DateOfBirth)
+	assert.NoError(t, err)
+	assert.Equal(t, `"1990-00-00"`, string(out))
+	assert.Equal(t, time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), doc.Properties.DateOfBirth.In(time.UTC))
+}
@@ -0,0 +1,227 @@
+package decision
+
+import (
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/esqimo/go-onfido"
+)
+
+// Values is a set of accepted values. It can be decoded from either a
+// single string or a list of strings.
+type Values []string
+
+// Contains reports whether v is one of the values.
+func (vs Values) Contains(v string) bool {
+	for _, s := range vs {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalJSON decodes a string or a list of strings.
+func (vs *Values) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*vs = Values{s}
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(data, &l); err != nil {
+		return err
+	}
+	*vs = l
+	return nil
+}
+
+// UnmarshalYAML decodes a string or a list of strings.
+func (vs *Values) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*vs = Values{value.Value}
+		return nil
+	}
+	var l []string
+	if err := value.Decode(&l); err != nil {
+		return err
+	}
+	*vs = l
+	return nil
+}
+
+// Condition matches a check, or the reports of a given name within it.
+//
+// Without Report the condition matches the check's status and result.
+// With Report, every report of that name must match, and at least one
+// must exist: Result and SubResult are matched against the report, or
+// against the breakdown at Breakdown (e.g. visual_authenticity.fonts) if
+// set. OnlyFailures matches reports whose failed breakdowns are all within
+// the given paths.
+//
+// A condition on a report never matches a check without that report, even
+// when negated, so that "not rejected" doesn't match a missing report.
+type Condition struct {
+	CheckStatus Values `json:"check_status,omitempty" yaml:"check_status,omitempty"`
+	CheckResult Values `json:"check_result,omitempty" yaml:"check_result,omitempty"`
+
+	Report       onfido.ReportName `json:"report,omitempty" yaml:"report,omitempty"`
+	Result       Values            `json:"result,omitempty" yaml:"result,omitempty"`
+	SubResult    Values            `json:"sub_result,omitempty" yaml:"sub_result,omitempty"`
+	Breakdown    string            `json:"breakdown,omitempty" yaml:"breakdown,omitempty"`
+	OnlyFailures Values            `json:"only_failures,omitempty" yaml:"only_failures,omitempty"`
+
+	// Not inverts the condition. It doesn't apply to a missing report.
+	Not bool `json:"not,omitempty" yaml:"not,omitempty"`
+}
+
+// CheckResultIs matches checks with one of the given results.
+func CheckResultIs(results ...onfido.CheckResult) Condition {
+	c := Condition{}
+	for _, r := range results {
+		c.CheckResult = append(c.CheckResult, string(r))
+	}
+	return c
+}
+
+// ReportResultIs matches when all reports of the given name have one of the given results.
+func ReportResultIs(name onfido.ReportName, results ...onfido.ReportResult) Condition {
+	c := Condition{Report: name}
+	for _, r := range results {
+		c.Result = append(c.Result, string(r))
+	}
+	return c
+}
+
+// ReportSubResultIs matches when all reports of the given name have one of the given sub results.
+func ReportSubResultIs(name onfido.ReportName, subResults ...onfido.ReportSubResult) Condition {
+	c := Condition{Report: name}
+	for _, r := range subResults {
+		c.SubResult = append(c.SubResult, string(r))
+	}
+	return c
+}
+
+// BreakdownIs matches when the breakdown at path has one of the given results
+// in all reports of the given name.
+func BreakdownIs(name onfido.ReportName, path string, results ...onfido.BreakdownResult) Condition {
+	c := Condition{Report: name, Breakdown: path}
+	for _, r := range results {
+		c.Result = append(c.Result, string(r))
+	}
+	return c
+}
+
+// OnlyFailures matches when all reports of the given name failed only
+// on breakdowns within the given paths.
+func OnlyFailures(name onfido.ReportName, paths ...string) Condition {
+	return Condition{Report: name, OnlyFailures: paths}
+}
+
+// Negate returns the inverse of the condition.
+func (c Condition) Negate() Condition {
+	c.Not = !c.Not
+	return c
+}
+
+// Validate checks that the condition is well formed.
+func (c Condition) Validate() error {
+	if c.Report == "" && (len(c.Result) > 0 || len(c.SubResult) > 0 || c.Breakdown != "" || len(c.OnlyFailures) > 0) {
+		return ErrMissingReport
+	}
+	return nil
+}
+
+// Match reports whether the condition matches the check.
+func (c Condition) Match(check *onfido.Check) bool {
+	if check == nil || !c.hasReport(check) {
+		return false
+	}
+	return c.match(check) != c.Not
+}
+
+// hasReport reports whether the check has a report the condition applies
+// to, always true for conditions on the check itself.
+func (c Condition) hasReport(check *onfido.Check) bool {
+	if c.Report == "" {
+		return true
+	}
+	for _, r := range check.Reports {
+		if r != nil && r.Name == c.Report {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Condition) match(check *onfido.Check) bool {
+	if len(c.CheckStatus) > 0 && !c.CheckStatus.Contains(string(check.Status)) {
+		return false
+	}
+	if len(c.CheckResult) > 0 && !c.CheckResult.Contains(string(check.Result)) {
+		return false
+	}
+	if c.Report == "" {
+		return true
+	}
+
+	for _, r := range check.Reports {
+		if r != nil && r.Name == c.Report && !c.matchReport(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Condition) matchReport(r *onfido.Report) bool {
+	if c.Breakdown != "" {
+		result, ok := breakdownResult(r.Breakdown, c.Breakdown)
+		if !ok {
+			return false
+		}
+		if len(c.Result) > 0 && !c.Result.Contains(result) {
+			return false
+		}
+	} else if len(c.Result) > 0 && !c.Result.Contains(string(r.Result)) {
+		return false
+	}
+	if len(c.SubResult) > 0 && !c.SubResult.Contains(string(r.SubResult)) {
+		return false
+	}
+	if len(c.OnlyFailures) > 0 {
+		failures := r.Breakdown.Failures()
+		if len(failures) == 0 {
+			return false
+		}
+		for _, f := range failures {
+			if !withinPaths(f.Path, c.OnlyFailures) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// breakdownResult returns the result of the breakdown at path, and whether it exists.
+func breakdownResult(b onfido.Breakdowns, path string) (string, bool) {
+	var result string
+	found := false
+	_ = b.Walk(func(n onfido.BreakdownNode) error {
+		if n.Path == path {
+			result, found = n.Result, true
+		}
+		return nil
+	})
+	return result, found
+}
+
+// withinPaths reports whether path equals or is nested under one of paths.
+func withinPaths(path string, paths Values) bool {
+	for _, p := range paths {
+		if path == p || len(path) > len(p) && path[:len(p)] == p && path[len(p)] == '.' {
+			return true
+		}
+	}
+	return false
+}
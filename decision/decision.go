@@ -0,0 +1,166 @@
+// Package decision evaluates declarative rules over Onfido checks and reports
+// to reach an approve, refer or reject outcome.
+//
+// Rules can be built in Go or loaded from JSON or YAML:
+//
+//	rules:
+//	  - name: watchlist hit
+//	    outcome: reject
+//	    when:
+//	      - report: watchlist_standard
+//	        result: consider
+//	  - name: only image quality failed
+//	    outcome: refer
+//	    when:
+//	      - report: document
+//	        only_failures: [image_integrity.image_quality]
+//	  - name: document and face clear
+//	    outcome: approve
+//	    when:
+//	      - report: document
+//	        result: clear
+//	      - report: facial_similarity_photo
+//	        result: clear
+package decision
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/esqimo/go-onfido"
+)
+
+// Outcome represents the outcome of a decision
+type Outcome string
+
+// Supported outcomes, in increasing order of severity
+const (
+	Approve Outcome = "approve"
+	Refer   Outcome = "refer"
+	Reject  Outcome = "reject"
+)
+
+// Errors returned when validating rules
+var (
+	ErrInvalidOutcome = errors.New("invalid outcome")
+	ErrEmptyRule      = errors.New("rule has no conditions")
+	ErrMissingReport  = errors.New("condition requires a report name")
+)
+
+func (o Outcome) severity() int {
+	switch o {
+	case Approve:
+		return 1
+	case Refer:
+		return 2
+	case Reject:
+		return 3
+	}
+	return 0
+}
+
+// Rule yields its outcome when all of its conditions match.
+type Rule struct {
+	Name    string      `json:"name" yaml:"name"`
+	Outcome Outcome     `json:"outcome" yaml:"outcome"`
+	When    []Condition `json:"when" yaml:"when"`
+}
+
+// Validate checks that the rule is well formed.
+func (r Rule) Validate() error {
+	if r.Outcome.severity() == 0 {
+		return fmt.Errorf("rule %q: %w: %q", r.Name, ErrInvalidOutcome, r.Outcome)
+	}
+	if len(r.When) == 0 {
+		return fmt.Errorf("rule %q: %w", r.Name, ErrEmptyRule)
+	}
+	for i, c := range r.When {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("rule %q: condition %d: %w", r.Name, i, err)
+		}
+	}
+	return nil
+}
+
+// Match reports whether all of the rule's conditions match the check.
+func (r Rule) Match(check *onfido.Check) bool {
+	if len(r.When) == 0 {
+		return false
+	}
+	for _, c := range r.When {
+		if !c.Match(check) {
+			return false
+		}
+	}
+	return true
+}
+
+// Decision is the result of evaluating rules against a check.
+type Decision struct {
+	Outcome Outcome
+	// Matched lists the rules which matched, in the order they were defined.
+	Matched []Rule
+}
+
+// Explanation returns the names of the matched rules.
+func (d Decision) Explanation() []string {
+	names := make([]string, len(d.Matched))
+	for i, r := range d.Matched {
+		names[i] = r.Name
+	}
+	return names
+}
+
+// Engine evaluates a set of rules.
+type Engine struct {
+	Rules []Rule
+	// Default is the outcome when no rule matches, Refer if empty.
+	Default Outcome
+}
+
+// New creates a new engine from the given rules.
+func New(rules ...Rule) (*Engine, error) {
+	e := &Engine{Rules: rules, Default: Refer}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Validate checks that all of the engine's rules are well formed.
+func (e *Engine) Validate() error {
+	if e.Default != "" && e.Default.severity() == 0 {
+		return fmt.Errorf("default: %w: %q", ErrInvalidOutcome, e.Default)
+	}
+	for _, r := range e.Rules {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Evaluate evaluates every rule against the check. The outcome is the most
+// severe outcome of the matched rules (reject over refer over approve), or
+// the default outcome if no rule matched.
+func (e *Engine) Evaluate(check *onfido.Check) Decision {
+	d := Decision{Outcome: e.Default}
+	if d.Outcome == "" {
+		d.Outcome = Refer
+	}
+
+	var outcome Outcome
+	for _, r := range e.Rules {
+		if !r.Match(check) {
+			continue
+		}
+		d.Matched = append(d.Matched, r)
+		if r.Outcome.severity() > outcome.severity() {
+			outcome = r.Outcome
+		}
+	}
+	if outcome != "" {
+		d.Outcome = outcome
+	}
+	return d
+}
@@ -0,0 +1,134 @@
+package decision
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/esqimo/go-onfido"
+)
+
+func loadCheck(t *testing.T, name string) *onfido.Check {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c onfido.Check
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	return &c
+}
+
+func TestEngine_Fixtures(t *testing.T) {
+	tests := []struct {
+		check   string
+		outcome Outcome
+		matched []string
+	}{
+		{"check_clear.json", Approve, []string{"document and face clear"}},
+		{"check_image_quality.json", Refer, []string{"only image quality failed"}},
+		{"check_watchlist_hit.json", Reject, []string{"watchlist hit"}},
+		{"check_fonts.json", Refer, []string{}},
+	}
+
+	for _, rules := range []string{"rules.yaml", "rules.json"} {
+		e, err := LoadFile(filepath.Join("testdata", rules))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(rules+"/"+tt.check, func(t *testing.T) {
+				d := e.Evaluate(loadCheck(t, tt.check))
+				assert.Equal(t, tt.outcome, d.Outcome)
+				assert.Equal(t, tt.matched, d.Explanation())
+			})
+		}
+	}
+}
+
+func TestEngine_GoAPI(t *testing.T) {
+	e, err := New(
+		Rule{
+			Name:    "fonts failed",
+			Outcome: Reject,
+			When:    []Condition{BreakdownIs(onfido.ReportNameDocument, "visual_authenticity.fonts", onfido.BreakdownConsider)},
+		},
+		Rule{
+			Name:    "document not suspected",
+			Outcome: Approve,
+			When: []Condition{
+				ReportSubResultIs(onfido.ReportNameDocument, onfido.ReportSubResultSuspected).Negate(),
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := e.Evaluate(loadCheck(t, "check_fonts.json"))
+	assert.Equal(t, Reject, d.Outcome)
+	assert.Equal(t, []string{"fonts failed"}, d.Explanation())
+
+	d = e.Evaluate(loadCheck(t, "check_clear.json"))
+	assert.Equal(t, Approve, d.Outcome)
+	assert.Equal(t, []string{"document not suspected"}, d.Explanation())
+}
+
+func TestCondition_MissingReport(t *testing.T) {
+	c := ReportResultIs(onfido.ReportNameKnownFaces, onfido.ReportResultClear)
+	assert.False(t, c.Match(loadCheck(t, "check_clear.json")))
+	assert.False(t, c.Negate().Match(loadCheck(t, "check_clear.json")))
+	assert.False(t, c.Negate().Match(nil))
+}
+
+func TestEngine_NegatedMissingReport(t *testing.T) {
+	e, err := New(Rule{
+		Name:    "document not rejected",
+		Outcome: Approve,
+		When:    []Condition{ReportSubResultIs(onfido.ReportNameDocument, onfido.ReportSubResultRejected).Negate()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := loadCheck(t, "check_clear.json")
+	assert.Equal(t, Approve, e.Evaluate(check).Outcome)
+
+	var reports []*onfido.Report
+	for _, r := range check.Reports {
+		if r.Name != onfido.ReportNameDocument {
+			reports = append(reports, r)
+		}
+	}
+	check.Reports = reports
+	assert.NotEqual(t, Approve, e.Evaluate(check).Outcome)
+}
+
+func TestCondition_CheckResult(t *testing.T) {
+	c := CheckResultIs(onfido.CheckResultClear)
+	assert.True(t, c.Match(loadCheck(t, "check_clear.json")))
+	assert.False(t, c.Match(loadCheck(t, "check_fonts.json")))
+}
+
+func TestNew_InvalidRules(t *testing.T) {
+	_, err := New(Rule{Name: "bad", Outcome: "maybe", When: []Condition{CheckResultIs(onfido.CheckResultClear)}})
+	assert.True(t, errors.Is(err, ErrInvalidOutcome))
+
+	_, err = New(Rule{Name: "empty", Outcome: Approve})
+	assert.True(t, errors.Is(err, ErrEmptyRule))
+
+	_, err = New(Rule{Name: "no report", Outcome: Approve, When: []Condition{{Breakdown: "fonts"}}})
+	assert.True(t, errors.Is(err, ErrMissingReport))
+}
+
+func TestLoadYAML_UnknownField(t *testing.T) {
+	_, err := LoadYAML(strings.NewReader("rules:\n  - name: typo\n    outcom: approve\n"))
+	assert.Error(t, err)
+}
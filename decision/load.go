@@ -0,0 +1,67 @@
+package decision
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ruleSet is the document format used by the JSON and YAML loaders.
+type ruleSet struct {
+	Default Outcome `json:"default,omitempty" yaml:"default,omitempty"`
+	Rules   []Rule  `json:"rules" yaml:"rules"`
+}
+
+func (rs ruleSet) engine() (*Engine, error) {
+	e := &Engine{Rules: rs.Rules, Default: rs.Default}
+	if e.Default == "" {
+		e.Default = Refer
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// LoadJSON creates an engine from a JSON rule set.
+func LoadJSON(r io.Reader) (*Engine, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var rs ruleSet
+	if err := dec.Decode(&rs); err != nil {
+		return nil, err
+	}
+	return rs.engine()
+}
+
+// LoadYAML creates an engine from a YAML rule set.
+func LoadYAML(r io.Reader) (*Engine, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	var rs ruleSet
+	if err := dec.Decode(&rs); err != nil {
+		return nil, err
+	}
+	return rs.engine()
+}
+
+// LoadFile creates an engine from a rule set file, decoded as YAML if the
+// file has a .yaml or .yml extension and as JSON otherwise.
+func LoadFile(path string) (*Engine, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		return LoadYAML(bytes.NewReader(data))
+	default:
+		return LoadJSON(bytes.NewReader(data))
+	}
+}
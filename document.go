@@ -40,20 +40,23 @@ type DocumentRequest struct {
 	File        io.ReadSeeker
 	Type        DocumentType
 	Side        DocumentSide
+	// IssuingCountry is optional, it must be a known code if set.
+	IssuingCountry CountryCode
 }
 
 // Document represents a document in Onfido API
 type Document struct {
-	ID           string       `json:"id,omitempty"`
-	CreatedAt    *time.Time   `json:"created_at,omitempty"`
-	Href         string       `json:"href,omitempty"`
-	DownloadHref string       `json:"download_href,omitempty"`
-	FileName     string       `json:"file_name,omitempty"`
-	FileType     string       `json:"file_type,omitempty"`
-	FileSize     int          `json:"file_size,omitempty"`
-	Type         DocumentType `json:"type,omitempty"`
-	Side         DocumentSide `json:"side,omitempty"`
-	ApplicantID  string       `json:"applicant_id,omitempty"`
+	ID             string       `json:"id,omitempty"`
+	CreatedAt      *time.Time   `json:"created_at,omitempty"`
+	Href           string       `json:"href,omitempty"`
+	DownloadHref   string       `json:"download_href,omitempty"`
+	FileName       string       `json:"file_name,omitempty"`
+	FileType       string       `json:"file_type,omitempty"`
+	FileSize       int          `json:"file_size,omitempty"`
+	Type           DocumentType `json:"type,omitempty"`
+	Side           DocumentSide `json:"side,omitempty"`
+	ApplicantID    string       `json:"applicant_id,omitempty"`
+	IssuingCountry CountryCode  `json:"issuing_country,omitempty"`
 }
 
 type DocumentDownload struct {
@@ -101,6 +104,10 @@ func createFormFile(writer *multipart.Writer, fieldname string, file io.ReadSeek
 // UploadDocument uploads a document for the provided applicant.
 // see https://documentation.onfido.com/?shell#upload-document
 func (c *client) UploadDocument(ctx context.Context, dr DocumentRequest) (*Document, error) {
+	if err := dr.IssuingCountry.Validate(); err != nil {
+		return nil, err
+	}
+
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
@@ -120,6 +127,11 @@ func (c *client) UploadDocument(ctx context.Context, dr DocumentRequest) (*Docum
 	if err := writer.WriteField("applicant_id", dr.ApplicantID); err != nil {
 		return nil, err
 	}
+	if dr.IssuingCountry != "" {
+		if err := writer.WriteField("issuing_country", string(dr.IssuingCountry)); err != nil {
+			return nil, err
+		}
+	}
 	if err := writer.Close(); err != nil {
 		return nil, err
 	}
@@ -151,20 +163,21 @@ func (c *client) GetDocument(ctx context.Context, id string) (*Document, error)
 // DownloadDocument returns the binary data representing the document image
 // see https://documentation.onfido.com/#download-document
 func (c *client) DownloadDocument(ctx context.Context, id string) (*DocumentDownload, error) {
-	req, err := c.newRequest("GET", "/documents/"+id+"/download", nil)
-	if err != nil {
-		return nil, err
-	}
-
 	var resp bytes.Buffer
-	_, err = c.do(ctx, req, &resp)
-	if err != nil {
-		return nil, fmt.Errorf("failed to download document: %w", err)
+	if err := c.downloadDocumentTo(ctx, id, &resp); err != nil {
+		return nil, err
 	}
-
 	return &DocumentDownload{
 		Data: resp.Bytes(),
-	}, err
+	}, nil
+}
+
+// downloadDocumentTo writes the document's file to w.
+func (c *client) downloadDocumentTo(ctx context.Context, id string, w io.Writer) error {
+	if err := c.download(ctx, "/documents/"+id+"/download", w); err != nil {
+		return fmt.Errorf("failed to download document: %w", err)
+	}
+	return nil
 }
 
 // DocumentIter represents a document iterator
@@ -0,0 +1,161 @@
+package onfido
+
+import (
+	"strings"
+)
+
+// DocumentNumberType represents the type of a number extracted from a document
+type DocumentNumberType string
+
+// Supported document number types
+const (
+	DocumentNumberTypeDocumentNumber DocumentNumberType = "document_number"
+	DocumentNumberTypePersonalNumber DocumentNumberType = "personal_number"
+	DocumentNumberTypeVersionNumber  DocumentNumberType = "version_number"
+)
+
+// DocumentNumber represents a number extracted from a document
+type DocumentNumber struct {
+	Type  DocumentNumberType `json:"type"`
+	Value string             `json:"value"`
+}
+
+// DocumentNumbers represents the list of numbers extracted from a document
+type DocumentNumbers []DocumentNumber
+
+// First returns the value of the first number of the given type.
+func (dn DocumentNumbers) First(t DocumentNumberType) (string, bool) {
+	for _, n := range dn {
+		if n.Type == t {
+			return n.Value, true
+		}
+	}
+	return "", false
+}
+
+// DocumentBarcode represents data read from a document's barcode
+type DocumentBarcode struct {
+	FirstName       string          `json:"first_name,omitempty"`
+	LastName        string          `json:"last_name,omitempty"`
+	MiddleName      string          `json:"middle_name,omitempty"`
+	Gender          string          `json:"gender,omitempty"`
+	DateOfBirth     Date            `json:"date_of_birth"`
+	DateOfExpiry    Date            `json:"date_of_expiry"`
+	IssuingCountry  string          `json:"issuing_country,omitempty"`
+	IssuingState    string          `json:"issuing_state,omitempty"`
+	DocumentType    DocumentType    `json:"document_type,omitempty"`
+	DocumentNumbers DocumentNumbers `json:"document_numbers,omitempty"`
+	Address         string          `json:"address,omitempty"`
+}
+
+// DocumentReportProperties represents the data extracted by a document report.
+// see https://documentation.onfido.com/#document-report
+type DocumentReportProperties struct {
+	DocumentType    DocumentType      `json:"document_type,omitempty"`
+	IssuingCountry  string            `json:"issuing_country,omitempty"`
+	IssuingState    string            `json:"issuing_state,omitempty"`
+	IssuingDate     Date              `json:"issuing_date"`
+	DateOfExpiry    Date              `json:"date_of_expiry"`
+	DateOfBirth     Date              `json:"date_of_birth"`
+	Nationality     string            `json:"nationality,omitempty"`
+	FirstName       string            `json:"first_name,omitempty"`
+	MiddleName      string            `json:"middle_name,omitempty"`
+	LastName        string            `json:"last_name,omitempty"`
+	Gender          string            `json:"gender,omitempty"`
+	DocumentNumbers DocumentNumbers   `json:"document_numbers,omitempty"`
+	Address         string            `json:"address,omitempty"`
+	MRZLine1        string            `json:"mrz_line1,omitempty"`
+	MRZLine2        string            `json:"mrz_line2,omitempty"`
+	MRZLine3        string            `json:"mrz_line3,omitempty"`
+	Barcode         []DocumentBarcode `json:"barcode,omitempty"`
+}
+
+// ExtractedIdentity is a normalised identity record extracted from a
+// document. Names and codes are upper-cased with whitespace collapsed,
+// document numbers have separators removed, and gender is reduced to
+// "M", "F" or "X", so records can be compared with ==.
+type ExtractedIdentity struct {
+	FirstName      string
+	LastName       string
+	DateOfBirth    Date
+	Gender         string
+	Nationality    string
+	IssuingCountry string
+	DocumentType   DocumentType
+	DocumentNumber string
+	DateOfExpiry   Date
+}
+
+// ExtractedIdentity normalises the extracted data into a single identity
+// record. Fields missing from the properties are filled from the first
+// barcode, if one was read.
+func (p DocumentReportProperties) ExtractedIdentity() ExtractedIdentity {
+	var bc DocumentBarcode
+	if len(p.Barcode) > 0 {
+		bc = p.Barcode[0]
+	}
+
+	id := ExtractedIdentity{
+		FirstName:      normaliseName(firstNonEmpty(p.FirstName, bc.FirstName)),
+		LastName:       normaliseName(firstNonEmpty(p.LastName, bc.LastName)),
+		DateOfBirth:    p.DateOfBirth,
+		Gender:         normaliseGender(firstNonEmpty(p.Gender, bc.Gender)),
+		Nationality:    strings.ToUpper(strings.TrimSpace(p.Nationality)),
+		IssuingCountry: strings.ToUpper(strings.TrimSpace(firstNonEmpty(p.IssuingCountry, bc.IssuingCountry))),
+		DocumentType:   p.DocumentType,
+		DateOfExpiry:   p.DateOfExpiry,
+	}
+	if id.DateOfBirth.IsZero() {
+		id.DateOfBirth = bc.DateOfBirth
+	}
+	if id.DateOfExpiry.IsZero() {
+		id.DateOfExpiry = bc.DateOfExpiry
+	}
+	if id.DocumentType == "" {
+		id.DocumentType = bc.DocumentType
+	}
+
+	number, ok := p.DocumentNumbers.First(DocumentNumberTypeDocumentNumber)
+	if !ok {
+		number, _ = bc.DocumentNumbers.First(DocumentNumberTypeDocumentNumber)
+	}
+	id.DocumentNumber = normaliseDocumentNumber(number)
+
+	return id
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func normaliseName(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), " "))
+}
+
+func normaliseGender(s string) string {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "M", "MALE":
+		return "M"
+	case "F", "FEMALE":
+		return "F"
+	case "":
+		return ""
+	default:
+		return "X"
+	}
+}
+
+func normaliseDocumentNumber(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '/', '.', '<':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(s))
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -13,22 +14,16 @@ func main() {
 		panic(err)
 	}
 
-	http.HandleFunc("/webhook/onfido", func(w http.ResponseWriter, req *http.Request) {
-		whReq, err := wh.ParseFromRequest(req)
-		if err != nil {
-			if err == onfido.ErrInvalidWebhookSignature {
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write([]byte("Invalid signature"))
-				return
-			}
-
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("Error occurred"))
-			return
-		}
-
-		fmt.Fprintf(w, "Webhook: %+v\n", whReq)
+	h := onfido.NewWebhookHandler(wh, onfido.WebhookHandlerOptions{})
+	h.OnCheckCompleted(func(ctx context.Context, wr *onfido.WebhookRequest) error {
+		fmt.Printf("Check completed: %s\n", wr.Payload.Object.ID)
+		return nil
+	})
+	h.Fallback(func(ctx context.Context, wr *onfido.WebhookRequest) error {
+		fmt.Printf("Webhook: %+v\n", wr.Payload)
+		return nil
 	})
 
+	http.Handle("/webhook/onfido", h)
 	http.ListenAndServe(":8080", nil)
 }
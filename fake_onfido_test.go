@@ -0,0 +1,252 @@
+package onfido
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeOnfido serves applicants, their resources, checks, reports and
+// webhooks from memory, for tests which need the API to keep state between
+// requests. Applicants are created as "app-1", "app-2", and so on, except
+// those with the last name "Invalid", which fail with a validation error.
+// Deleted applicants are not found, or returned with deleteAt if set.
+type fakeOnfido struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	applicants map[string]*Applicant
+	order      []string
+	created    []Applicant
+	deleted    map[string]bool
+	deleteAt   string
+	// resources holds the IDs of each collection's resources by applicant
+	resources map[string]map[string][]string
+	checks    map[string]*Check
+	reports   map[string][]*Report
+	webhooks  []*WebhookRef
+	calls     []string
+}
+
+func newFakeOnfido(t *testing.T) *fakeOnfido {
+	f := &fakeOnfido{
+		applicants: make(map[string]*Applicant),
+		deleted:    make(map[string]bool),
+		resources:  make(map[string]map[string][]string),
+		checks:     make(map[string]*Check),
+		reports:    make(map[string][]*Report),
+	}
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(v))
+	}
+	notFound := func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, err := w.Write([]byte(`{"error": {"type": "resource_not_found", "message": "not found"}}`))
+		assert.NoError(t, err)
+	}
+
+	m := mux.NewRouter()
+	m.HandleFunc("/applicants", func(w http.ResponseWriter, r *http.Request) {
+		var a Applicant
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&a))
+		if a.LastName == "Invalid" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, err := w.Write([]byte(`{"error": {"type": "validation_error", "message": "There was a validation error on this request", "fields": {"last_name": ["is invalid"]}}}`))
+			assert.NoError(t, err)
+			return
+		}
+		writeJSON(w, f.add(a))
+	}).Methods("POST")
+	m.HandleFunc("/applicants", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		var list Applicants
+		for _, id := range f.order {
+			if a, ok := f.applicants[id]; ok {
+				list.Applicants = append(list.Applicants, a)
+			}
+		}
+		writeJSON(w, list)
+	}).Methods("GET")
+	m.HandleFunc("/applicants/{id}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		id := mux.Vars(r)["id"]
+		switch a, ok := f.applicants[id]; {
+		case ok:
+			writeJSON(w, a)
+		case f.deleted[id] && f.deleteAt != "":
+			writeJSON(w, map[string]string{"id": id, "delete_at": f.deleteAt})
+		default:
+			notFound(w)
+		}
+	}).Methods("GET")
+	m.HandleFunc("/applicants/{id}", func(w http.ResponseWriter, r *http.Request) {
+		f.delete(mux.Vars(r)["id"])
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("DELETE")
+	for _, collection := range []string{"documents", "live_photos", "live_videos"} {
+		collection := collection
+		m.HandleFunc("/"+collection, func(w http.ResponseWriter, r *http.Request) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			list := []map[string]string{}
+			for _, id := range f.resources[collection][r.URL.Query().Get("applicant_id")] {
+				list = append(list, map[string]string{"id": id})
+			}
+			writeJSON(w, map[string]interface{}{collection: list})
+		}).Methods("GET")
+	}
+	m.HandleFunc("/checks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		c, ok := f.checks[mux.Vars(r)["id"]]
+		if !ok {
+			notFound(w)
+			return
+		}
+		writeJSON(w, CheckRetrieved{ID: c.ID, Status: c.Status, Href: c.Href})
+	}).Methods("GET")
+	m.HandleFunc("/checks", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		var list Checks
+		for _, c := range f.checks {
+			if c.ApplicantID == r.URL.Query().Get("applicant_id") {
+				list.Checks = append(list.Checks, c)
+			}
+		}
+		writeJSON(w, list)
+	}).Methods("GET")
+	m.HandleFunc("/reports", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		writeJSON(w, Reports{Reports: f.reports[r.URL.Query().Get("check_id")]})
+	}).Methods("GET")
+	m.HandleFunc("/webhooks/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		writeJSON(w, WebhookRefs{WebhookRefs: f.webhooks})
+	}).Methods("GET")
+	m.HandleFunc("/webhooks", func(w http.ResponseWriter, r *http.Request) {
+		var wr WebhookRefRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&wr))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.calls = append(f.calls, "create "+wr.URL)
+		ref := &WebhookRef{ID: "webhook-" + strconv.Itoa(len(f.webhooks)+1), URL: wr.URL, Enabled: wr.Enabled, Token: "secret"}
+		f.webhooks = append(f.webhooks, ref)
+		writeJSON(w, ref)
+	}).Methods("POST")
+	m.HandleFunc("/webhooks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var wr WebhookRefRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&wr))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		id := mux.Vars(r)["id"]
+		f.calls = append(f.calls, "update "+id)
+		for _, ref := range f.webhooks {
+			if ref.ID == id {
+				ref.URL, ref.Enabled, ref.Environments, ref.Events = wr.URL, wr.Enabled, wr.Environments, wr.Events
+				writeJSON(w, ref)
+				return
+			}
+		}
+		notFound(w)
+	}).Methods("PUT")
+	m.HandleFunc("/webhooks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		id := mux.Vars(r)["id"]
+		f.calls = append(f.calls, "delete "+id)
+		for i, ref := range f.webhooks {
+			if ref.ID == id {
+				f.webhooks = append(f.webhooks[:i], f.webhooks[i+1:]...)
+				break
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("DELETE")
+	f.Server = httptest.NewServer(m)
+	return f
+}
+
+// add stores an applicant as if created through the API.
+func (f *fakeOnfido) add(a Applicant) *Applicant {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.created = append(f.created, a)
+	a.ID = "app-" + strconv.Itoa(len(f.created))
+	f.applicants[a.ID] = &a
+	f.order = append(f.order, a.ID)
+	return &a
+}
+
+func (f *fakeOnfido) delete(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.applicants, id)
+	f.deleted[id] = true
+}
+
+func (f *fakeOnfido) isDeleted(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.deleted[id]
+}
+
+// createdApplicants returns the applicants created, as they were sent.
+func (f *fakeOnfido) createdApplicants() []Applicant {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Applicant(nil), f.created...)
+}
+
+// applicantID returns the ID of the first applicant created with the
+// first name.
+func (f *fakeOnfido) applicantID(firstName string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, id := range f.order {
+		if a := f.applicants[id]; a != nil && a.FirstName == firstName {
+			return id
+		}
+	}
+	return ""
+}
+
+// addResource stores a document, live photo or live video of the applicant.
+func (f *fakeOnfido) addResource(collection, applicantID, id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.resources[collection] == nil {
+		f.resources[collection] = make(map[string][]string)
+	}
+	f.resources[collection][applicantID] = append(f.resources[collection][applicantID], id)
+}
+
+// setCheck stores the check of the applicant, or changes its status.
+func (f *fakeOnfido) setCheck(id, applicantID string, status CheckStatus, reports ...*Report) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.checks[id] = &Check{ID: id, ApplicantID: applicantID, Status: status, Href: "/v3.1/checks/" + id}
+	if len(reports) > 0 {
+		f.reports[id] = reports
+	}
+}
+
+// webhookCalls returns the webhook changes made, in order.
+func (f *fakeOnfido) webhookCalls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
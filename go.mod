@@ -7,4 +7,5 @@ require (
 	github.com/stretchr/testify v1.6.0
 	github.com/tomnomnom/linkheader v0.0.0-20180905144013-02ca5825eb80
 	github.com/uw-labs/go-onfido v0.0.0-20200220102243-a3e5f74e6744
+	gopkg.in/yaml.v3 v3.0.1
 )
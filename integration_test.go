@@ -36,7 +36,7 @@ func TestIntegrationCreateApplicant_ApplicantCreated(t *testing.T) {
 	assert.Equal(t, expected.Email, a.Email)
 	assert.Equal(t, expected.FirstName, a.FirstName)
 	assert.Equal(t, expected.LastName, a.LastName)
-	assert.Equal(t, expected.Addresses, a.Addresses)
+	assert.Equal(t, expected.Address, a.Address)
 	assert.Equal(t, expected.IDNumbers, a.IDNumbers)
 
 	applicantID = a.ID
@@ -59,7 +59,7 @@ func TestIntegrationGetApplicant_ApplicantRetrieved(t *testing.T) {
 	assert.Equal(t, expected.Email, a.Email)
 	assert.Equal(t, expected.FirstName, a.FirstName)
 	assert.Equal(t, expected.LastName, a.LastName)
-	assert.Equal(t, expected.Addresses, a.Addresses)
+	assert.Equal(t, expected.Address, a.Address)
 	assert.Equal(t, expected.IDNumbers, a.IDNumbers)
 }
 
@@ -116,7 +116,8 @@ func TestIntegrationUploadDocument_DocumentUploaded(t *testing.T) {
 	}
 
 	expected := getDefaultDocument()
-	d, err := getOnfidoClient().UploadDocument(context.Background(), applicantID, *expected)
+	expected.ApplicantID = applicantID
+	d, err := getOnfidoClient().UploadDocument(context.Background(), *expected)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -143,7 +144,7 @@ func TestIntegrationGetDocument_DocumentRetrieved(t *testing.T) {
 
 	expected := getDefaultDocument()
 	file := expected.File.(*os.File)
-	d, err := getOnfidoClient().GetDocument(context.Background(), applicantID, documentID)
+	d, err := getOnfidoClient().GetDocument(context.Background(), documentID)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -192,15 +193,13 @@ func getDefaultApplicant() *onfido.Applicant {
 				Value: "1234567",
 			},
 		},
-		Addresses: []onfido.Address{
-			{
-				FlatNumber: "10",
-				Street:     "Baker Street",
-				Town:       "London",
-				Postcode:   "W1U 8ED",
-				Country:    "GBR",
-				StartDate:  "2017-12-05",
-			},
+		Address: onfido.Address{
+			FlatNumber: "10",
+			Street:     "Baker Street",
+			Town:       "London",
+			Postcode:   "W1U 8ED",
+			Country:    "GBR",
+			StartDate:  "2017-12-05",
 		},
 	}
 }
@@ -218,12 +217,12 @@ func getDefaultDocument() *onfido.DocumentRequest {
 	}
 }
 
-func getOnfidoClient() onfido.Client {
+func getOnfidoClient() onfido.OnfidoClient {
 	if *onfidoToken == "" {
 		panic("onfido token not set")
 	}
 	client := onfido.NewClient(*onfidoToken)
-	if client.Token.Prod() {
+	if client.Token().Prod() {
 		panic("do not use a production token for integration tests")
 	}
 	return client
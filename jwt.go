@@ -4,19 +4,99 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"time"
 )
 
+// SDK token errors
+var (
+	ErrInvalidReferrer         = errors.New("invalid referrer pattern")
+	ErrInvalidCrossDeviceURL   = errors.New("invalid cross device url")
+	ErrInvalidCustomExpiry     = errors.New("invalid sdk token custom expiry")
+	ErrMissingSdkTokenTarget   = errors.New("sdk token requires a referrer or an application id")
+	ErrAmbiguousSdkTokenTarget = errors.New("sdk token accepts either a referrer or an application id, not both")
+)
+
+// referrerPattern matches referrer patterns such as https://*.example.com/*:
+// an http, https or * scheme, a host optionally prefixed with a *. wildcard
+// or a lone *, an optional port and a path which may contain wildcards.
+var referrerPattern = regexp.MustCompile(`^(https?|\*)://(\*|(\*\.)?[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)*)(:\d+)?/\S*$`)
+
 // SdkToken represents the response for a request for a JWT token
 type SdkToken struct {
-	ApplicantID   string `json:"applicant_id,omitempty"`
-	Referrer      string `json:"referrer,omitempty"`
-	ApplicationID string `json:"application_id,omitempty"`
-	Token         string `json:"token,omitempty"`
+	ApplicantID    string `json:"applicant_id,omitempty"`
+	Referrer       string `json:"referrer,omitempty"`
+	ApplicationID  string `json:"application_id,omitempty"`
+	CrossDeviceURL string `json:"cross_device_url,omitempty"`
+	Token          string `json:"token,omitempty"`
+}
+
+// SdkTokenRequest represents a request for a JWT token. Set either Referrer
+// for the Javascript SDK, or ApplicationID for the iOS, Android and React
+// Native SDKs.
+type SdkTokenRequest struct {
+	ApplicantID string
+	// Referrer is the pattern of the URLs allowed to use the token, e.g.
+	// https://*.example.com/*
+	Referrer string
+	// ApplicationID is the application's bundle or package ID.
+	ApplicationID string
+	// CrossDeviceURL is the https URL used by the cross device flow, if
+	// it is hosted on a custom domain.
+	CrossDeviceURL string
+	// CustomExpiry is how long the token is valid for, Onfido's default
+	// if not set. It must be a whole number of seconds.
+	CustomExpiry time.Duration
+}
+
+// Validate checks the request's fields are well formed.
+func (r SdkTokenRequest) Validate() error {
+	if r.ApplicantID == "" {
+		return ErrInvalidApplicantID
+	}
+	if r.Referrer == "" && r.ApplicationID == "" {
+		return ErrMissingSdkTokenTarget
+	}
+	if r.Referrer != "" && r.ApplicationID != "" {
+		return ErrAmbiguousSdkTokenTarget
+	}
+	if r.Referrer != "" && !referrerPattern.MatchString(r.Referrer) {
+		return ErrInvalidReferrer
+	}
+	if r.CrossDeviceURL != "" {
+		u, err := url.Parse(r.CrossDeviceURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return ErrInvalidCrossDeviceURL
+		}
+	}
+	if r.CustomExpiry < 0 || r.CustomExpiry%time.Second != 0 {
+		return ErrInvalidCustomExpiry
+	}
+	return nil
+}
+
+// MarshalJSON encodes the request as expected by the Onfido API.
+func (r SdkTokenRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ApplicantID    string `json:"applicant_id"`
+		Referrer       string `json:"referrer,omitempty"`
+		ApplicationID  string `json:"application_id,omitempty"`
+		CrossDeviceURL string `json:"cross_device_url,omitempty"`
+		CustomExpiry   int64  `json:"custom_expiry,omitempty"`
+	}{
+		ApplicantID:    r.ApplicantID,
+		Referrer:       r.Referrer,
+		ApplicationID:  r.ApplicationID,
+		CrossDeviceURL: r.CrossDeviceURL,
+		CustomExpiry:   int64(r.CustomExpiry / time.Second),
+	})
 }
 
 // NewSdkTokenWeb returns a JWT token to used by the Javascript SDK.
 func (c *client) NewSdkTokenWeb(ctx context.Context, applicantID, referrer string) (*SdkToken, error) {
-	return c.sdkTokenRequest(ctx, &SdkToken{
+	return c.NewSdkToken(ctx, SdkTokenRequest{
 		ApplicantID: applicantID,
 		Referrer:    referrer,
 	})
@@ -24,14 +104,20 @@ func (c *client) NewSdkTokenWeb(ctx context.Context, applicantID, referrer strin
 
 // NewSdkTokenMobile returns a JWT token to used by the iOS and Android SDKs.
 func (c *client) NewSdkTokenMobile(ctx context.Context, applicantID, applicationID string) (*SdkToken, error) {
-	return c.sdkTokenRequest(ctx, &SdkToken{
+	return c.NewSdkToken(ctx, SdkTokenRequest{
 		ApplicantID:   applicantID,
 		ApplicationID: applicationID,
 	})
 }
 
-func (c *client) sdkTokenRequest(ctx context.Context, t *SdkToken) (*SdkToken, error) {
-	jsonStr, err := json.Marshal(t)
+// NewSdkToken returns a JWT token to be used by the SDKs.
+// see https://documentation.onfido.com/?shell#generate-sdk-token
+func (c *client) NewSdkToken(ctx context.Context, tr SdkTokenRequest) (*SdkToken, error) {
+	if err := tr.Validate(); err != nil {
+		return nil, err
+	}
+
+	jsonStr, err := json.Marshal(tr)
 	if err != nil {
 		return nil, err
 	}
@@ -46,7 +132,11 @@ func (c *client) sdkTokenRequest(ctx context.Context, t *SdkToken) (*SdkToken, e
 		return nil, err
 	}
 
-	t.Token = resp.Token
-
-	return t, err
+	return &SdkToken{
+		ApplicantID:    tr.ApplicantID,
+		Referrer:       tr.Referrer,
+		ApplicationID:  tr.ApplicationID,
+		CrossDeviceURL: tr.CrossDeviceURL,
+		Token:          resp.Token,
+	}, nil
 }
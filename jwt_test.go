@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
@@ -69,3 +70,77 @@ func TestNewSdkTokenWeb_ApplicantsRetrieved(t *testing.T) {
 	assert.Equal(t, expected.Referrer, token.Referrer)
 	assert.Equal(t, expected.Token, token.Token)
 }
+
+func TestNewSdkToken(t *testing.T) {
+	m := mux.NewRouter()
+	m.HandleFunc("/sdk_token", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]interface{}{
+			"applicant_id":     "klj25h2jk5j4k5jk35",
+			"application_id":   "com.example.app",
+			"cross_device_url": "https://verify.example.com",
+			"custom_expiry":    float64(5400),
+		}, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, wErr := w.Write([]byte(`{"token": "423423m4n234czxKJKDLF"}`))
+		assert.NoError(t, wErr)
+	}).Methods("POST")
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	token, err := client.NewSdkToken(context.Background(), SdkTokenRequest{
+		ApplicantID:    "klj25h2jk5j4k5jk35",
+		ApplicationID:  "com.example.app",
+		CrossDeviceURL: "https://verify.example.com",
+		CustomExpiry:   90 * time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &SdkToken{
+		ApplicantID:    "klj25h2jk5j4k5jk35",
+		ApplicationID:  "com.example.app",
+		CrossDeviceURL: "https://verify.example.com",
+		Token:          "423423m4n234czxKJKDLF",
+	}, token)
+}
+
+func TestSdkTokenRequest_Validate(t *testing.T) {
+	for _, referrer := range []string{
+		"https://*.example.com/*",
+		"https://example.com/onboarding/*",
+		"*://*/*",
+		"http://localhost:8080/",
+	} {
+		assert.NoError(t, SdkTokenRequest{ApplicantID: "123", Referrer: referrer}.Validate(), referrer)
+	}
+	for _, referrer := range []string{
+		"example.com",
+		"https://*.example.com",
+		"ftp://example.com/*",
+		"https://ex*ample.com/*",
+		"https://*.example.com/ *",
+	} {
+		assert.Equal(t, ErrInvalidReferrer, SdkTokenRequest{ApplicantID: "123", Referrer: referrer}.Validate(), referrer)
+	}
+
+	assert.Equal(t, ErrInvalidApplicantID, SdkTokenRequest{Referrer: "https://example.com/*"}.Validate())
+	assert.Equal(t, ErrMissingSdkTokenTarget, SdkTokenRequest{ApplicantID: "123"}.Validate())
+	assert.Equal(t, ErrAmbiguousSdkTokenTarget, SdkTokenRequest{ApplicantID: "123", Referrer: "https://example.com/*", ApplicationID: "com.example.app"}.Validate())
+	assert.Equal(t, ErrInvalidCrossDeviceURL, SdkTokenRequest{ApplicantID: "123", ApplicationID: "com.example.app", CrossDeviceURL: "http://verify.example.com"}.Validate())
+	assert.NoError(t, SdkTokenRequest{ApplicantID: "123", ApplicationID: "com.example.app", CustomExpiry: time.Hour}.Validate())
+	for _, expiry := range []time.Duration{-time.Minute, time.Millisecond, 90*time.Second + 500*time.Millisecond} {
+		assert.Equal(t, ErrInvalidCustomExpiry, SdkTokenRequest{ApplicantID: "123", ApplicationID: "com.example.app", CustomExpiry: expiry}.Validate(), expiry)
+	}
+}
+
+func TestNewSdkTokenWeb_InvalidReferrer(t *testing.T) {
+	client := NewClient("123").(*client)
+	_, err := client.NewSdkTokenWeb(context.Background(), "123", "example.com")
+	assert.Equal(t, ErrInvalidReferrer, err)
+}
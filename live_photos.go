@@ -1,7 +1,11 @@
 package onfido
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"time"
 )
 
@@ -16,6 +20,32 @@ type LivePhoto struct {
 	FileSize     int32      `json:"file_size,omitempty"`
 }
 
+// LivePhotoDownload represents a downloaded live photo
+type LivePhotoDownload struct {
+	// Data is the binary data of the live photo
+	Data []byte
+}
+
+// DownloadLivePhoto returns the binary data representing the live photo.
+// see https://documentation.onfido.com/#download-live-photo
+func (c *client) DownloadLivePhoto(ctx context.Context, id string) (*LivePhotoDownload, error) {
+	var resp bytes.Buffer
+	if err := c.downloadLivePhotoTo(ctx, id, &resp); err != nil {
+		return nil, err
+	}
+	return &LivePhotoDownload{
+		Data: resp.Bytes(),
+	}, nil
+}
+
+// downloadLivePhotoTo writes the live photo's file to w.
+func (c *client) downloadLivePhotoTo(ctx context.Context, id string, w io.Writer) error {
+	if err := c.download(ctx, "/live_photos/"+id+"/download", w); err != nil {
+		return fmt.Errorf("failed to download live photo: %w", err)
+	}
+	return nil
+}
+
 // LivePhotoIter represents a LivePhoto iterator
 type LivePhotoIter struct {
 	*iter
@@ -12,6 +12,32 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func TestDownloadLivePhoto(t *testing.T) {
+	mockPhotoID := "93672a37-8223-48b9-a440-3b5cb52a8e4b"
+	m := mux.NewRouter()
+	m.HandleFunc("/live_photos/{photoId}/download", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		assert.Equal(t, mockPhotoID, vars["photoId"])
+
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.WriteHeader(http.StatusOK)
+		_, wErr := w.Write([]byte("this is a photo"))
+		assert.NoError(t, wErr)
+	}).Methods("GET")
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	photoDownload, err := client.DownloadLivePhoto(context.Background(), mockPhotoID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []byte("this is a photo"), photoDownload.Data)
+}
+
 func TestLivePhotos_List(t *testing.T) {
 	applicantID := "541d040b-89f8-444b-8921-16b1333bf1c6"
 	createdAt := time.Now()
@@ -5,7 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
+	"io"
 	"time"
 )
 
@@ -29,19 +29,21 @@ type LiveVideoDownload struct {
 // DownloadLiveVideo returns the binary data representing the video.
 // see https://documentation.onfido.com/#download-live-video
 func (c *client) DownloadLiveVideo(ctx context.Context, id string) (*LiveVideoDownload, error) {
-	req, err := c.newRequest(http.MethodGet, "/live_videos/"+id+"/download", nil)
-	if err != nil {
-		return nil, err
-	}
-
 	var resp bytes.Buffer
-	_, err = c.do(ctx, req, &resp)
-	if err != nil {
-		return nil, fmt.Errorf("failed to download live video: %w", err)
+	if err := c.downloadLiveVideoTo(ctx, id, &resp); err != nil {
+		return nil, err
 	}
 	return &LiveVideoDownload{
 		Data: resp.Bytes(),
-	}, err
+	}, nil
+}
+
+// downloadLiveVideoTo writes the live video's file to w.
+func (c *client) downloadLiveVideoTo(ctx context.Context, id string, w io.Writer) error {
+	if err := c.download(ctx, "/live_videos/"+id+"/download", w); err != nil {
+		return fmt.Errorf("failed to download live video: %w", err)
+	}
+	return nil
 }
 
 // liveVideoIter represents a LiveVideo iterator
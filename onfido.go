@@ -26,6 +26,7 @@ type OnfidoClient interface {
 	SetHTTPClient(client HTTPRequester)
 	NewSdkTokenWeb(ctx context.Context, applicantID, referrer string) (*SdkToken, error)
 	NewSdkTokenMobile(ctx context.Context, applicantID, applicationID string) (*SdkToken, error)
+	NewSdkToken(ctx context.Context, tr SdkTokenRequest) (*SdkToken, error)
 	GetReport(ctx context.Context, id string) (*Report, error)
 	ResumeReport(ctx context.Context, id string) error
 	CancelReport(ctx context.Context, id string) error
@@ -35,32 +36,44 @@ type OnfidoClient interface {
 	UploadDocument(ctx context.Context, dr DocumentRequest) (*Document, error)
 	DownloadDocument(ctx context.Context, id string) (*DocumentDownload, error)
 	ListLivePhotos(applicantID string) *LivePhotoIter
+	DownloadLivePhoto(ctx context.Context, id string) (*LivePhotoDownload, error)
 	DownloadLiveVideo(ctx context.Context, id string) (*LiveVideoDownload, error)
 	ListLiveVideos(applicantID string) LiveVideoIter
 	CreateApplicant(ctx context.Context, a Applicant) (*Applicant, error)
 	DeleteApplicant(ctx context.Context, id string) error
 	GetApplicant(ctx context.Context, id string) (*Applicant, error)
+	RestoreApplicant(ctx context.Context, id string) error
 	ListApplicants() *ApplicantIter
+	ListApplicantsWithOptions(opts ListApplicantsOptions) *ApplicantIter
 	UpdateApplicant(ctx context.Context, a Applicant) (*Applicant, error)
+	UpdateApplicantFields(ctx context.Context, id string, p ApplicantPatch) (*Applicant, error)
+	InventoryApplicant(ctx context.Context, id string) (*ApplicantInventory, error)
+	EraseApplicant(ctx context.Context, id string, opts EraseApplicantOptions) (*ErasureReceipt, error)
+	ExportApplicant(ctx context.Context, id string, w io.Writer) error
+	BulkCreateApplicants(ctx context.Context, source io.Reader, opts BulkCreateOptions) (*BulkCreateSummary, error)
 	CreateCheck(ctx context.Context, cr CheckRequest) (*Check, error)
 	GetCheck(ctx context.Context, id string) (*CheckRetrieved, error)
 	GetCheckExpanded(ctx context.Context, id string) (*Check, error)
 	ResumeCheck(ctx context.Context, id string) (*Check, error)
+	DownloadCheck(ctx context.Context, id string) (*CheckDownload, error)
 	ListChecks(applicantID string) *CheckIter
 	CreateWebhook(ctx context.Context, wr WebhookRefRequest) (*WebhookRef, error)
 	UpdateWebhook(ctx context.Context, id string, wr WebhookRefRequest) (*WebhookRef, error)
 	DeleteWebhook(ctx context.Context, id string) error
 	ListWebhooks() *WebhookRefIter
+	SyncWebhooks(ctx context.Context, desired []WebhookRefRequest, opts SyncWebhooksOptions) (*WebhookSyncPlan, error)
 	PickAddresses(postcode string) *PickerIter
 	GetResource(ctx context.Context, href string, v interface{}) error
+	ResolveWebhookObject(ctx context.Context, wr *WebhookRequest) (interface{}, error)
 	Token() Token
 }
 
 // Client represents an Onfido API client
 type client struct {
-	endpoint   string
-	httpClient HTTPRequester
-	token      Token
+	endpoint    string
+	httpClient  HTTPRequester
+	token       Token
+	objectCache *objectCache
 }
 
 func (c *client) SetHTTPClient(client HTTPRequester) {
@@ -99,6 +112,14 @@ func (e *Error) Error() string {
 	return "an unknown error occurred"
 }
 
+// isNotFound reports whether err is an Onfido error for a resource which
+// doesn't exist or has been deleted.
+func isNotFound(err error) bool {
+	var oErr *Error
+	return errors.As(err, &oErr) && oErr.Resp != nil &&
+		(oErr.Resp.StatusCode == http.StatusNotFound || oErr.Resp.StatusCode == http.StatusGone)
+}
+
 // Token is an Onfido authentication token
 type Token string
 
@@ -128,9 +149,10 @@ func NewClientFromEnv() (OnfidoClient, error) {
 // NewClient creates a new Onfido client.
 func NewClient(token string) OnfidoClient {
 	return &client{
-		endpoint:   DefaultEndpoint,
-		httpClient: http.DefaultClient,
-		token:      Token(token),
+		endpoint:    DefaultEndpoint,
+		httpClient:  http.DefaultClient,
+		token:       Token(token),
+		objectCache: newObjectCache(webhookObjectCacheTTL),
 	}
 }
 
@@ -171,6 +193,16 @@ func (c *client) newRequest(method, uri string, body io.Reader) (*http.Request,
 	return req, nil
 }
 
+// download writes the body of a GET of path to w.
+func (c *client) download(ctx context.Context, path string, w io.Writer) error {
+	req, err := c.newRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(ctx, req, w)
+	return err
+}
+
 func (c *client) do(ctx context.Context, req *http.Request, v interface{}) (*http.Response, error) {
 	req = req.WithContext(ctx)
 	resp, err := c.httpClient.Do(req)
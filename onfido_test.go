@@ -41,6 +41,20 @@ func TestError_FallbackMsg(t *testing.T) {
 	}
 }
 
+func TestIsNotFound(t *testing.T) {
+	for code, want := range map[int]bool{
+		http.StatusNotFound:            true,
+		http.StatusGone:                true,
+		http.StatusUnprocessableEntity: false,
+	} {
+		err := fmt.Errorf("wrapped: %w", &Error{Resp: &http.Response{StatusCode: code}})
+		assert.Equal(t, want, isNotFound(err), "status %d", code)
+	}
+	assert.False(t, isNotFound(&Error{}))
+	assert.False(t, isNotFound(errors.New("not found")))
+	assert.False(t, isNotFound(nil))
+}
+
 func TestToken_IsProd(t *testing.T) {
 	tokens := []struct {
 		token  string
@@ -49,7 +49,7 @@ type Report struct {
 	ID         string                 `json:"id,omitempty"`
 	Name       ReportName             `json:"name,omitempty"`
 	CreatedAt  *time.Time             `json:"created_at,omitempty"`
-	Status     string                 `json:"status,omitempty"`
+	Status     ReportStatus           `json:"status,omitempty"`
 	Result     ReportResult           `json:"result,omitempty"`
 	SubResult  ReportSubResult        `json:"sub_result,omitempty"`
 	Href       string                 `json:"href,omitempty"`
@@ -58,6 +58,21 @@ type Report struct {
 	Properties Properties             `json:"properties,omitempty"`
 	CheckID    string                 `json:"check_id,omitempty"`
 	Documents  []DocumentProcessed    `json:"documents,omitempty"`
+
+	// Raw is the report's JSON, including any fields not modelled here.
+	Raw json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the report, retaining the raw JSON.
+func (r *Report) UnmarshalJSON(data []byte) error {
+	type report Report
+	var v report
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*r = Report(v)
+	r.Raw = append(json.RawMessage(nil), data...)
+	return nil
 }
 
 // Reports represents a list of reports from the Onfido API
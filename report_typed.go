@@ -0,0 +1,469 @@
+package onfido
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnexpectedReportName is returned by the typed report accessors when the
+// report is not of the requested kind.
+var ErrUnexpectedReportName = errors.New("unexpected report name")
+
+// ReportHeader contains the fields shared by every typed report.
+type ReportHeader struct {
+	ID        string          `json:"id,omitempty"`
+	Name      ReportName      `json:"name,omitempty"`
+	CreatedAt *time.Time      `json:"created_at,omitempty"`
+	Status    ReportStatus    `json:"status,omitempty"`
+	Result    ReportResult    `json:"result,omitempty"`
+	SubResult ReportSubResult `json:"sub_result,omitempty"`
+	Href      string          `json:"href,omitempty"`
+	CheckID   string          `json:"check_id,omitempty"`
+}
+
+// ReportDocumentRef references a document processed by a report.
+type ReportDocumentRef struct {
+	ID string `json:"id"`
+}
+
+// ScoreProperties contains the score returned by breakdowns such as face_match.
+type ScoreProperties struct {
+	Score *float64 `json:"score,omitempty"`
+}
+
+// ScoredSubBreakdown represents a sub-breakdown which carries a score.
+type ScoredSubBreakdown struct {
+	Result     *BreakdownSubResult `json:"result"`
+	Properties ScoreProperties     `json:"properties"`
+}
+
+// ResultBreakdown represents a breakdown which only carries a result.
+type ResultBreakdown struct {
+	Result *BreakdownResult `json:"result"`
+}
+
+// decodeAs decodes the report into v if the report is one of names. The
+// report is decoded from its raw JSON, or marshalled from its fields if it
+// wasn't decoded from JSON.
+func (r *Report) decodeAs(v interface{}, names ...ReportName) error {
+	found := false
+	for _, n := range names {
+		if r.Name == n {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: %q", ErrUnexpectedReportName, r.Name)
+	}
+
+	data := []byte(r.Raw)
+	if len(data) == 0 {
+		var err error
+		if data, err = json.Marshal(r); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ------------------------------------------------------------------
+// Document
+// ------------------------------------------------------------------
+
+// DocumentReport represents a document report.
+// see https://documentation.onfido.com/#document-report
+type DocumentReport struct {
+	ReportHeader
+	Breakdown  DocumentReportBreakdown  `json:"breakdown"`
+	Properties DocumentReportProperties `json:"properties"`
+	Documents  []ReportDocumentRef      `json:"documents"`
+}
+
+// DocumentReportBreakdown represents the breakdown of a document report.
+type DocumentReportBreakdown struct {
+	DataComparison      *DocumentDataComparisonBreakdown     `json:"data_comparison,omitempty"`
+	DataValidation      *DocumentDataValidationBreakdown     `json:"data_validation,omitempty"`
+	DataConsistency     *DocumentDataConsistencyBreakdown    `json:"data_consistency,omitempty"`
+	ImageIntegrity      *DocumentImageIntegrityBreakdown     `json:"image_integrity,omitempty"`
+	VisualAuthenticity  *DocumentVisualAuthenticityBreakdown `json:"visual_authenticity,omitempty"`
+	AgeValidation       *DocumentAgeValidationBreakdown      `json:"age_validation,omitempty"`
+	PoliceRecord        *ResultBreakdown                     `json:"police_record,omitempty"`
+	CompromisedDocument *ResultBreakdown                     `json:"compromised_document,omitempty"`
+	IssuingAuthority    *DocumentIssuingAuthorityBreakdown   `json:"issuing_authority,omitempty"`
+}
+
+// DocumentDataFieldsBreakdown contains the per field sub-breakdowns shared by
+// the data_comparison and data_consistency breakdowns.
+type DocumentDataFieldsBreakdown struct {
+	DocumentType    *SubBreakdown `json:"document_type,omitempty"`
+	IssuingCountry  *SubBreakdown `json:"issuing_country,omitempty"`
+	Nationality     *SubBreakdown `json:"nationality,omitempty"`
+	DocumentNumbers *SubBreakdown `json:"document_numbers,omitempty"`
+	FirstName       *SubBreakdown `json:"first_name,omitempty"`
+	LastName        *SubBreakdown `json:"last_name,omitempty"`
+	Gender          *SubBreakdown `json:"gender,omitempty"`
+	DateOfBirth     *SubBreakdown `json:"date_of_birth,omitempty"`
+	DateOfExpiry    *SubBreakdown `json:"date_of_expiry,omitempty"`
+}
+
+// DocumentDataComparisonBreakdown represents the data_comparison breakdown.
+type DocumentDataComparisonBreakdown struct {
+	Result    *BreakdownResult            `json:"result"`
+	Breakdown DocumentDataFieldsBreakdown `json:"breakdown"`
+}
+
+// DocumentDataConsistencyBreakdown represents the data_consistency breakdown.
+type DocumentDataConsistencyBreakdown struct {
+	Result    *BreakdownResult            `json:"result"`
+	Breakdown DocumentDataFieldsBreakdown `json:"breakdown"`
+}
+
+// DocumentDataValidationBreakdown represents the data_validation breakdown.
+type DocumentDataValidationBreakdown struct {
+	Result    *BreakdownResult `json:"result"`
+	Breakdown struct {
+		DocumentNumbers    *SubBreakdown `json:"document_numbers,omitempty"`
+		DocumentExpiration *SubBreakdown `json:"document_expiration,omitempty"`
+		ExpiryDate         *SubBreakdown `json:"expiry_date,omitempty"`
+		DateOfBirth        *SubBreakdown `json:"date_of_birth,omitempty"`
+		Gender             *SubBreakdown `json:"gender,omitempty"`
+		MRZ                *SubBreakdown `json:"mrz,omitempty"`
+		Barcode            *SubBreakdown `json:"barcode,omitempty"`
+	} `json:"breakdown"`
+}
+
+// DocumentImageIntegrityBreakdown represents the image_integrity breakdown.
+type DocumentImageIntegrityBreakdown struct {
+	Result    *BreakdownResult `json:"result"`
+	Breakdown struct {
+		ImageQuality              *SubBreakdown `json:"image_quality,omitempty"`
+		SupportedDocument         *SubBreakdown `json:"supported_document,omitempty"`
+		ColourPicture             *SubBreakdown `json:"colour_picture,omitempty"`
+		ConclusiveDocumentQuality *SubBreakdown `json:"conclusive_document_quality,omitempty"`
+	} `json:"breakdown"`
+}
+
+// DocumentVisualAuthenticityBreakdown represents the visual_authenticity breakdown.
+type DocumentVisualAuthenticityBreakdown struct {
+	Result    *BreakdownResult `json:"result"`
+	Breakdown struct {
+		Fonts                   *SubBreakdown `json:"fonts,omitempty"`
+		PictureFaceIntegrity    *SubBreakdown `json:"picture_face_integrity,omitempty"`
+		Template                *SubBreakdown `json:"template,omitempty"`
+		SecurityFeatures        *SubBreakdown `json:"security_features,omitempty"`
+		OriginalDocumentPresent *SubBreakdown `json:"original_document_present,omitempty"`
+		DigitalTampering        *SubBreakdown `json:"digital_tampering,omitempty"`
+		FaceDetection           *SubBreakdown `json:"face_detection,omitempty"`
+		Other                   *SubBreakdown `json:"other,omitempty"`
+	} `json:"breakdown"`
+}
+
+// DocumentAgeValidationBreakdown represents the age_validation breakdown.
+type DocumentAgeValidationBreakdown struct {
+	Result    *BreakdownResult `json:"result"`
+	Breakdown struct {
+		MinimumAcceptedAge *SubBreakdown `json:"minimum_accepted_age,omitempty"`
+	} `json:"breakdown"`
+}
+
+// DocumentIssuingAuthorityBreakdown represents the issuing_authority breakdown.
+type DocumentIssuingAuthorityBreakdown struct {
+	Result    *BreakdownResult `json:"result"`
+	Breakdown struct {
+		NFCActiveAuthentication  *SubBreakdown `json:"nfc_active_authentication,omitempty"`
+		NFCPassiveAuthentication *SubBreakdown `json:"nfc_passive_authentication,omitempty"`
+	} `json:"breakdown"`
+}
+
+// AsDocument decodes the report as a document report.
+func (r *Report) AsDocument() (*DocumentReport, error) {
+	var v DocumentReport
+	if err := r.decodeAs(&v,
+		ReportNameDocument,
+		ReportNameDocumentWithAddress,
+		ReportNameDocumentWithDrivingLicense,
+	); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ------------------------------------------------------------------
+// Facial similarity photo
+// ------------------------------------------------------------------
+
+// FacialSimilarityPhotoReport represents a facial similarity photo report.
+// see https://documentation.onfido.com/#facial-similarity-photo-report
+type FacialSimilarityPhotoReport struct {
+	ReportHeader
+	Breakdown  FacialSimilarityPhotoBreakdown `json:"breakdown"`
+	Properties Properties                     `json:"properties"`
+	Documents  []ReportDocumentRef            `json:"documents"`
+}
+
+// FacialSimilarityPhotoBreakdown represents the breakdown of a facial similarity photo report.
+type FacialSimilarityPhotoBreakdown struct {
+	FaceComparison *struct {
+		Result    *BreakdownResult `json:"result"`
+		Breakdown struct {
+			FaceMatch *ScoredSubBreakdown `json:"face_match,omitempty"`
+		} `json:"breakdown"`
+	} `json:"face_comparison,omitempty"`
+	ImageIntegrity *struct {
+		Result    *BreakdownResult `json:"result"`
+		Breakdown struct {
+			FaceDetected    *SubBreakdown `json:"face_detected,omitempty"`
+			SourceIntegrity *SubBreakdown `json:"source_integrity,omitempty"`
+		} `json:"breakdown"`
+	} `json:"image_integrity,omitempty"`
+	VisualAuthenticity *struct {
+		Result    *BreakdownResult `json:"result"`
+		Breakdown struct {
+			SpoofingDetection *ScoredSubBreakdown `json:"spoofing_detection,omitempty"`
+		} `json:"breakdown"`
+	} `json:"visual_authenticity,omitempty"`
+}
+
+// FaceMatchScore returns the face match score, if present.
+func (r *FacialSimilarityPhotoReport) FaceMatchScore() (float64, bool) {
+	fc := r.Breakdown.FaceComparison
+	if fc == nil || fc.Breakdown.FaceMatch == nil || fc.Breakdown.FaceMatch.Properties.Score == nil {
+		return 0, false
+	}
+	return *fc.Breakdown.FaceMatch.Properties.Score, true
+}
+
+// AsFacialSimilarityPhoto decodes the report as a facial similarity photo report.
+func (r *Report) AsFacialSimilarityPhoto() (*FacialSimilarityPhotoReport, error) {
+	var v FacialSimilarityPhotoReport
+	if err := r.decodeAs(&v, ReportNameFacialSimilarityPhoto); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ------------------------------------------------------------------
+// Watchlist
+// ------------------------------------------------------------------
+
+// WatchlistReport represents any of the watchlist reports (standard,
+// enhanced, peps only and sanctions only).
+// see https://documentation.onfido.com/#watchlist-report
+type WatchlistReport struct {
+	ReportHeader
+	Breakdown  WatchlistBreakdown  `json:"breakdown"`
+	Properties WatchlistProperties `json:"properties"`
+}
+
+// WatchlistBreakdown represents the breakdown of a watchlist report.
+type WatchlistBreakdown struct {
+	Sanction                   *ResultBreakdown `json:"sanction,omitempty"`
+	PoliticallyExposedPerson   *ResultBreakdown `json:"politically_exposed_person,omitempty"`
+	LegalAndRegulatoryWarnings *ResultBreakdown `json:"legal_and_regulatory_warnings,omitempty"`
+	AdverseMedia               *ResultBreakdown `json:"adverse_media,omitempty"`
+	MonitoredLists             *ResultBreakdown `json:"monitored_lists,omitempty"`
+}
+
+// WatchlistProperties represents the properties of a watchlist report.
+type WatchlistProperties struct {
+	Records []WatchlistRecord `json:"records"`
+}
+
+// WatchlistRecord represents a single watchlist match.
+type WatchlistRecord struct {
+	FullName    string                   `json:"full_name,omitempty"`
+	Position    string                   `json:"position,omitempty"`
+	Sources     []WatchlistSource        `json:"sources,omitempty"`
+	Associates  []map[string]interface{} `json:"associates,omitempty"`
+	Address     []Address                `json:"address,omitempty"`
+	DateOfBirth []string                 `json:"date_of_birth,omitempty"`
+	Keywords    []string                 `json:"keywords,omitempty"`
+	Aliases     []string                 `json:"aliases,omitempty"`
+	ExternalID  string                   `json:"external_id,omitempty"`
+	Report      string                   `json:"report,omitempty"`
+	AllSources  []WatchlistSource        `json:"all_sources,omitempty"`
+}
+
+// WatchlistSource represents the source of a watchlist record.
+type WatchlistSource struct {
+	SourceName   string `json:"source_name,omitempty"`
+	SourceURL    string `json:"source_url,omitempty"`
+	SourceFormat string `json:"source_format,omitempty"`
+	Keyword      string `json:"keyword,omitempty"`
+}
+
+// AsWatchlist decodes the report as a watchlist report.
+func (r *Report) AsWatchlist() (*WatchlistReport, error) {
+	var v WatchlistReport
+	if err := r.decodeAs(&v,
+		ReportNameWatchlistStandard,
+		ReportNameWatchlistEnhanced,
+		ReportNameWatchlistPepsOnly,
+		ReportNameWatchlistSanctionsOnly,
+	); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ------------------------------------------------------------------
+// Identity enhanced
+// ------------------------------------------------------------------
+
+// IdentityEnhancedReport represents an identity enhanced report.
+// see https://documentation.onfido.com/#identity-enhanced-report
+type IdentityEnhancedReport struct {
+	ReportHeader
+	Breakdown  IdentityEnhancedBreakdown  `json:"breakdown"`
+	Properties IdentityEnhancedProperties `json:"properties"`
+}
+
+// IdentityEnhancedBreakdown represents the breakdown of an identity enhanced report.
+type IdentityEnhancedBreakdown struct {
+	Sources *struct {
+		Result    *BreakdownResult `json:"result"`
+		Breakdown struct {
+			TotalSources *SubBreakdown `json:"total_sources,omitempty"`
+		} `json:"breakdown"`
+	} `json:"sources,omitempty"`
+	Address *struct {
+		Result    *BreakdownResult `json:"result"`
+		Breakdown struct {
+			CreditAgencies    *SubBreakdown `json:"credit_agencies,omitempty"`
+			TelephoneDatabase *SubBreakdown `json:"telephone_database,omitempty"`
+			VotingRegister    *SubBreakdown `json:"voting_register,omitempty"`
+		} `json:"breakdown"`
+	} `json:"address,omitempty"`
+	DateOfBirth *struct {
+		Result    *BreakdownResult `json:"result"`
+		Breakdown struct {
+			CreditAgencies *SubBreakdown `json:"credit_agencies,omitempty"`
+			VotingRegister *SubBreakdown `json:"voting_register,omitempty"`
+		} `json:"breakdown"`
+	} `json:"date_of_birth,omitempty"`
+	Mortality *ResultBreakdown `json:"mortality,omitempty"`
+}
+
+// IdentityEnhancedProperties represents the properties of an identity enhanced report.
+type IdentityEnhancedProperties struct {
+	MatchedAddress   *int             `json:"matched_address,omitempty"`
+	MatchedAddresses []MatchedAddress `json:"matched_addresses,omitempty"`
+}
+
+// MatchedAddress represents an address matched by an identity enhanced report.
+type MatchedAddress struct {
+	ID         int      `json:"id"`
+	MatchTypes []string `json:"match_types"`
+}
+
+// AsIdentityEnhanced decodes the report as an identity enhanced report.
+func (r *Report) AsIdentityEnhanced() (*IdentityEnhancedReport, error) {
+	var v IdentityEnhancedReport
+	if err := r.decodeAs(&v, ReportNameIdentityEnhanced); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ------------------------------------------------------------------
+// Known faces
+// ------------------------------------------------------------------
+
+// KnownFacesReport represents a known faces report.
+// see https://documentation.onfido.com/#known-faces-report
+type KnownFacesReport struct {
+	ReportHeader
+	Breakdown  KnownFacesBreakdown  `json:"breakdown"`
+	Properties KnownFacesProperties `json:"properties"`
+}
+
+// KnownFacesBreakdown represents the breakdown of a known faces report.
+type KnownFacesBreakdown struct {
+	PreviouslySeenFaces *ResultBreakdown `json:"previously_seen_faces,omitempty"`
+	ImageIntegrity      *ResultBreakdown `json:"image_integrity,omitempty"`
+}
+
+// KnownFacesProperties represents the properties of a known faces report.
+type KnownFacesProperties struct {
+	Matches []KnownFaceMatch `json:"matches"`
+}
+
+// KnownFaceMatch represents an applicant whose face matched the report's applicant.
+type KnownFaceMatch struct {
+	ApplicantID string  `json:"applicant_id"`
+	Score       float64 `json:"score"`
+	MediaID     string  `json:"media_id,omitempty"`
+	MediaType   string  `json:"media_type,omitempty"`
+	Suspected   bool    `json:"suspected,omitempty"`
+}
+
+// AsKnownFaces decodes the report as a known faces report.
+func (r *Report) AsKnownFaces() (*KnownFacesReport, error) {
+	var v KnownFacesReport
+	if err := r.decodeAs(&v, ReportNameKnownFaces); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ------------------------------------------------------------------
+// Proof of address
+// ------------------------------------------------------------------
+
+// ProofOfAddressReport represents a proof of address report.
+// see https://documentation.onfido.com/#proof-of-address-report
+type ProofOfAddressReport struct {
+	ReportHeader
+	Breakdown  ProofOfAddressBreakdown  `json:"breakdown"`
+	Properties ProofOfAddressProperties `json:"properties"`
+	Documents  []ReportDocumentRef      `json:"documents"`
+}
+
+// ProofOfAddressBreakdown represents the breakdown of a proof of address report.
+type ProofOfAddressBreakdown struct {
+	DocumentClassification *struct {
+		Result    *BreakdownResult `json:"result"`
+		Breakdown struct {
+			IssueDate         *SubBreakdown `json:"issue_date,omitempty"`
+			SupportedDocument *SubBreakdown `json:"supported_document,omitempty"`
+			SummaryPeriod     *SubBreakdown `json:"summary_period,omitempty"`
+		} `json:"breakdown"`
+	} `json:"document_classification,omitempty"`
+	ImageIntegrity *struct {
+		Result    *BreakdownResult `json:"result"`
+		Breakdown struct {
+			ImageQuality *SubBreakdown `json:"image_quality,omitempty"`
+		} `json:"breakdown"`
+	} `json:"image_integrity,omitempty"`
+	DataComparison *struct {
+		Result    *BreakdownResult `json:"result"`
+		Breakdown struct {
+			Address    *SubBreakdown `json:"address,omitempty"`
+			FirstNames *SubBreakdown `json:"first_names,omitempty"`
+			LastNames  *SubBreakdown `json:"last_names,omitempty"`
+		} `json:"breakdown"`
+	} `json:"data_comparison,omitempty"`
+}
+
+// ProofOfAddressProperties represents the properties of a proof of address report.
+type ProofOfAddressProperties struct {
+	DocumentType  string `json:"document_type,omitempty"`
+	Issuer        string `json:"issuer,omitempty"`
+	IssueDate     string `json:"issue_date,omitempty"`
+	SummaryPeriod string `json:"summary_period,omitempty"`
+	FirstNames    string `json:"first_names,omitempty"`
+	LastNames     string `json:"last_names,omitempty"`
+	Address       string `json:"address,omitempty"`
+}
+
+// AsProofOfAddress decodes the report as a proof of address report.
+func (r *Report) AsProofOfAddress() (*ProofOfAddressReport, error) {
+	var v ProofOfAddressReport
+	if err := r.decodeAs(&v, ReportNameProofOfAddress); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
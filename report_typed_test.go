@@ -0,0 +1,306 @@
+package onfido
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const documentReportJSON = `{
+	"id": "ce62d838-56f8-4ea5-98be-e7166d1dc33d",
+	"name": "document",
+	"status": "complete",
+	"result": "consider",
+	"sub_result": "caution",
+	"check_id": "541d040b-89f8-444b-8921-16b1333bf1c6",
+	"breakdown": {
+		"visual_authenticity": {
+			"result": "consider",
+			"breakdown": {
+				"fonts": {"result": "consider", "properties": {}},
+				"template": {"result": "clear", "properties": {}}
+			}
+		},
+		"police_record": {"result": "clear"}
+	},
+	"properties": {
+		"document_type": "passport",
+		"issuing_country": "GBR"
+	},
+	"documents": [{"id": "7568bd30-e5e5-4dcb-8aea-80d8ecddaf0f"}]
+}`
+
+func TestReport_AsDocument(t *testing.T) {
+	var r Report
+	if err := json.Unmarshal([]byte(documentReportJSON), &r); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := r.AsDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, r.ID, doc.ID)
+	assert.Equal(t, ReportSubResultCaution, doc.SubResult)
+	if assert.NotNil(t, doc.Breakdown.VisualAuthenticity) {
+		assert.Equal(t, BreakdownConsider, *doc.Breakdown.VisualAuthenticity.Result)
+		assert.Equal(t, SubBreakdownConsider, *doc.Breakdown.VisualAuthenticity.Breakdown.Fonts.Result)
+		assert.Equal(t, SubBreakdownClear, *doc.Breakdown.VisualAuthenticity.Breakdown.Template.Result)
+	}
+	if assert.NotNil(t, doc.Breakdown.PoliceRecord) {
+		assert.Equal(t, BreakdownClear, *doc.Breakdown.PoliceRecord.Result)
+	}
+	assert.Nil(t, doc.Breakdown.DataComparison)
+	assert.Equal(t, "GBR", doc.Properties.IssuingCountry)
+	assert.Equal(t, []ReportDocumentRef{{ID: "7568bd30-e5e5-4dcb-8aea-80d8ecddaf0f"}}, doc.Documents)
+}
+
+func TestReport_AsDocument_UnexpectedName(t *testing.T) {
+	r := Report{Name: ReportNameWatchlistStandard}
+	_, err := r.AsDocument()
+	if !errors.Is(err, ErrUnexpectedReportName) {
+		t.Fatalf("expected ErrUnexpectedReportName, got %v", err)
+	}
+}
+
+func TestReport_AsFacialSimilarityPhoto(t *testing.T) {
+	var r Report
+	err := json.Unmarshal([]byte(`{
+		"name": "facial_similarity_photo",
+		"result": "clear",
+		"breakdown": {
+			"face_comparison": {
+				"result": "clear",
+				"breakdown": {"face_match": {"result": "clear", "properties": {"score": 0.86}}}
+			}
+		}
+	}`), &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs, err := r.AsFacialSimilarityPhoto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	score, ok := fs.FaceMatchScore()
+	assert.True(t, ok)
+	assert.Equal(t, 0.86, score)
+}
+
+func TestReport_AsWatchlist(t *testing.T) {
+	var r Report
+	err := json.Unmarshal([]byte(`{
+		"name": "watchlist_standard",
+		"result": "consider",
+		"breakdown": {"sanction": {"result": "consider"}},
+		"properties": {"records": [{"full_name": "John Smith", "sources": [{"source_name": "OFAC"}]}]}
+	}`), &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wl, err := r.AsWatchlist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, BreakdownConsider, *wl.Breakdown.Sanction.Result)
+	if assert.Len(t, wl.Properties.Records, 1) {
+		assert.Equal(t, "John Smith", wl.Properties.Records[0].FullName)
+		assert.Equal(t, "OFAC", wl.Properties.Records[0].Sources[0].SourceName)
+	}
+}
+
+func TestReport_AsKnownFaces_NotDecodedFromJSON(t *testing.T) {
+	r := Report{
+		Name: ReportNameKnownFaces,
+		Properties: Properties{
+			"matches": []interface{}{
+				map[string]interface{}{"applicant_id": "abc", "score": 0.9},
+			},
+		},
+	}
+
+	kf, err := r.AsKnownFaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []KnownFaceMatch{{ApplicantID: "abc", Score: 0.9}}, kf.Properties.Matches)
+}
+
+func TestDocumentReportProperties_ExtractedIdentity(t *testing.T) {
+	var r Report
+	err := json.Unmarshal([]byte(`{
+		"name": "document",
+		"properties": {
+			"document_type": "passport",
+			"issuing_country": "gbr",
+			"nationality": "GBR",
+			"first_name": " Jane  Mary ",
+			"last_name": "Doe",
+			"gender": "Female",
+			"date_of_birth": "1990-02-28",
+			"date_of_expiry": "2030-01-01",
+			"document_numbers": [
+				{"type": "personal_number", "value": "999"},
+				{"type": "document_number", "value": "12-345 678"}
+			],
+			"barcode": [{"first_name": "JANE", "date_of_birth": "1990-02-28"}]
+		}
+	}`), &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := r.AsDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Date{Year: 2030, Month: 1, Day: 1}, doc.Properties.DateOfExpiry)
+
+	id := doc.Properties.ExtractedIdentity()
+	assert.Equal(t, ExtractedIdentity{
+		FirstName:      "JANE MARY",
+		LastName:       "DOE",
+		DateOfBirth:    Date{Year: 1990, Month: 2, Day: 28},
+		Gender:         "F",
+		Nationality:    "GBR",
+		IssuingCountry: "GBR",
+		DocumentType:   DocumentTypePassport,
+		DocumentNumber: "12345678",
+		DateOfExpiry:   Date{Year: 2030, Month: 1, Day: 1},
+	}, id)
+}
+
+func TestDocumentReportProperties_ExtractedIdentity_BarcodeFallback(t *testing.T) {
+	p := DocumentReportProperties{
+		Barcode: []DocumentBarcode{{
+			FirstName:       "john",
+			LastName:        "smith",
+			DateOfBirth:     Date{Year: 1980, Month: 5, Day: 1},
+			DocumentNumbers: DocumentNumbers{{Type: DocumentNumberTypeDocumentNumber, Value: "d123"}},
+		}},
+	}
+
+	id := p.ExtractedIdentity()
+	assert.Equal(t, "JOHN", id.FirstName)
+	assert.Equal(t, "SMITH", id.LastName)
+	assert.Equal(t, Date{Year: 1980, Month: 5, Day: 1}, id.DateOfBirth)
+	assert.Equal(t, "D123", id.DocumentNumber)
+}
+
+func TestReport_DecodesRawJSON(t *testing.T) {
+	var r Report
+	if err := json.Unmarshal([]byte(documentReportJSON), &r); err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, documentReportJSON, string(r.Raw))
+
+	// Fields not modelled by Report are still decoded
+	var v struct {
+		Breakdown struct {
+			PoliceRecord map[string]interface{} `json:"police_record"`
+		} `json:"breakdown"`
+		Properties struct {
+			DocumentType string `json:"document_type"`
+		} `json:"properties"`
+	}
+	r.Properties["document_type"] = "national_identity_card"
+	if err := r.decodeAs(&v, ReportNameDocument); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]interface{}{"result": "clear"}, v.Breakdown.PoliceRecord)
+	assert.Equal(t, "passport", v.Properties.DocumentType)
+}
+
+func TestReport_AsIdentityEnhanced(t *testing.T) {
+	var r Report
+	err := json.Unmarshal([]byte(`{
+		"name": "identity_enhanced",
+		"result": "clear",
+		"breakdown": {
+			"sources": {"result": "clear", "breakdown": {"total_sources": {"result": "clear", "properties": {"total_number_of_sources": "3"}}}},
+			"address": {"result": "clear", "breakdown": {"credit_agencies": {"result": "clear", "properties": {}}}},
+			"date_of_birth": {"result": "clear", "breakdown": {"voting_register": {"result": "clear", "properties": {}}}},
+			"mortality": {"result": "clear"}
+		},
+		"properties": {
+			"matched_address": 19099121,
+			"matched_addresses": [{"id": 19099121, "match_types": ["credit_agencies", "voting_register"]}]
+		}
+	}`), &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ie, err := r.AsIdentityEnhanced()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotNil(t, ie.Breakdown.Sources) {
+		assert.Equal(t, SubBreakdownClear, *ie.Breakdown.Sources.Breakdown.TotalSources.Result)
+		assert.Equal(t, "3", ie.Breakdown.Sources.Breakdown.TotalSources.Properties["total_number_of_sources"])
+	}
+	if assert.NotNil(t, ie.Breakdown.Address) {
+		assert.Equal(t, SubBreakdownClear, *ie.Breakdown.Address.Breakdown.CreditAgencies.Result)
+		assert.Nil(t, ie.Breakdown.Address.Breakdown.VotingRegister)
+	}
+	if assert.NotNil(t, ie.Breakdown.Mortality) {
+		assert.Equal(t, BreakdownClear, *ie.Breakdown.Mortality.Result)
+	}
+	if assert.NotNil(t, ie.Properties.MatchedAddress) {
+		assert.Equal(t, 19099121, *ie.Properties.MatchedAddress)
+	}
+	assert.Equal(t, []MatchedAddress{{ID: 19099121, MatchTypes: []string{"credit_agencies", "voting_register"}}}, ie.Properties.MatchedAddresses)
+
+	_, err = (&Report{Name: ReportNameDocument}).AsIdentityEnhanced()
+	assert.True(t, errors.Is(err, ErrUnexpectedReportName))
+}
+
+func TestReport_AsProofOfAddress(t *testing.T) {
+	var r Report
+	err := json.Unmarshal([]byte(`{
+		"name": "proof_of_address",
+		"result": "consider",
+		"breakdown": {
+			"document_classification": {"result": "clear", "breakdown": {"issue_date": {"result": "clear", "properties": {}}}},
+			"data_comparison": {"result": "consider", "breakdown": {"address": {"result": "consider", "properties": {}}}}
+		},
+		"properties": {
+			"document_type": "bank_building_society_statement",
+			"issuer": "Lloyds",
+			"issue_date": "2020-05-01",
+			"address": "1 Main Street, London"
+		},
+		"documents": [{"id": "poa-1"}]
+	}`), &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	poa, err := r.AsProofOfAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotNil(t, poa.Breakdown.DocumentClassification) {
+		assert.Equal(t, SubBreakdownClear, *poa.Breakdown.DocumentClassification.Breakdown.IssueDate.Result)
+	}
+	if assert.NotNil(t, poa.Breakdown.DataComparison) {
+		assert.Equal(t, BreakdownConsider, *poa.Breakdown.DataComparison.Result)
+		assert.Equal(t, SubBreakdownConsider, *poa.Breakdown.DataComparison.Breakdown.Address.Result)
+	}
+	assert.Nil(t, poa.Breakdown.ImageIntegrity)
+	assert.Equal(t, ProofOfAddressProperties{
+		DocumentType: "bank_building_society_statement",
+		Issuer:       "Lloyds",
+		IssueDate:    "2020-05-01",
+		Address:      "1 Main Street, London",
+	}, poa.Properties)
+	assert.Equal(t, []ReportDocumentRef{{ID: "poa-1"}}, poa.Documents)
+
+	_, err = (&Report{Name: ReportNameDocument}).AsProofOfAddress()
+	assert.True(t, errors.Is(err, ErrUnexpectedReportName))
+}
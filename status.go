@@ -0,0 +1,208 @@
+package onfido
+
+import "sync"
+
+// ReportStatus represents the status of a report
+type ReportStatus string
+
+// Supported report statuses
+const (
+	ReportStatusAwaitingData     ReportStatus = "awaiting_data"
+	ReportStatusAwaitingApproval ReportStatus = "awaiting_approval"
+	ReportStatusComplete         ReportStatus = "complete"
+	ReportStatusWithdrawn        ReportStatus = "withdrawn"
+	ReportStatusPaused           ReportStatus = "paused"
+	ReportStatusCancelled        ReportStatus = "cancelled"
+)
+
+var checkTransitions = map[CheckStatus][]CheckStatus{
+	CheckStatusInProgress:        {CheckStatusAwaitingApplicant, CheckStatusComplete, CheckStatusWithdrawn, CheckStatusPaused},
+	CheckStatusAwaitingApplicant: {CheckStatusInProgress, CheckStatusComplete, CheckStatusWithdrawn, CheckStatusPaused},
+	CheckStatusPaused:            {CheckStatusInProgress, CheckStatusReopened, CheckStatusComplete, CheckStatusWithdrawn},
+	CheckStatusReopened:          {CheckStatusInProgress, CheckStatusAwaitingApplicant, CheckStatusComplete, CheckStatusWithdrawn, CheckStatusPaused},
+	CheckStatusComplete:          {CheckStatusReopened},
+	CheckStatusWithdrawn:         {},
+}
+
+var reportTransitions = map[ReportStatus][]ReportStatus{
+	ReportStatusAwaitingData:     {ReportStatusAwaitingApproval, ReportStatusComplete, ReportStatusWithdrawn, ReportStatusPaused, ReportStatusCancelled},
+	ReportStatusAwaitingApproval: {ReportStatusComplete, ReportStatusWithdrawn, ReportStatusPaused, ReportStatusCancelled},
+	ReportStatusPaused:           {ReportStatusAwaitingData, ReportStatusAwaitingApproval, ReportStatusComplete, ReportStatusWithdrawn, ReportStatusCancelled},
+	ReportStatusComplete:         {},
+	ReportStatusWithdrawn:        {},
+	ReportStatusCancelled:        {},
+}
+
+// IsKnown reports whether the status is one of the documented check statuses.
+func (s CheckStatus) IsKnown() bool {
+	_, ok := checkTransitions[s]
+	return ok
+}
+
+// IsTerminal reports whether the check has finished. Note a complete
+// check can still be reopened.
+func (s CheckStatus) IsTerminal() bool {
+	return s == CheckStatusComplete || s == CheckStatusWithdrawn
+}
+
+// CanTransition reports whether a check can move from one status to another.
+func (s CheckStatus) CanTransition(to CheckStatus) bool {
+	return CanTransitionCheck(s, to)
+}
+
+// CanTransitionCheck reports whether a check can move from one status to another.
+func CanTransitionCheck(from, to CheckStatus) bool {
+	for _, s := range checkTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsKnown reports whether the status is one of the documented report statuses.
+func (s ReportStatus) IsKnown() bool {
+	_, ok := reportTransitions[s]
+	return ok
+}
+
+// IsTerminal reports whether the report has finished.
+func (s ReportStatus) IsTerminal() bool {
+	return s == ReportStatusComplete || s == ReportStatusWithdrawn || s == ReportStatusCancelled
+}
+
+// CanTransition reports whether a report can move from one status to another.
+func (s ReportStatus) CanTransition(to ReportStatus) bool {
+	return CanTransitionReport(s, to)
+}
+
+// CanTransitionReport reports whether a report can move from one status to another.
+func CanTransitionReport(from, to ReportStatus) bool {
+	for _, s := range reportTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// EventOrder represents the result of observing a webhook event
+type EventOrder int
+
+// Possible event orders
+const (
+	// EventInOrder means the event is a legal transition from the last known status
+	EventInOrder EventOrder = iota
+	// EventDuplicate means the event repeats the last known status
+	EventDuplicate
+	// EventOutOfOrder means the event cannot follow the last known status,
+	// e.g. report.completed arriving after check.completed
+	EventOutOfOrder
+)
+
+func (o EventOrder) String() string {
+	switch o {
+	case EventInOrder:
+		return "in_order"
+	case EventDuplicate:
+		return "duplicate"
+	case EventOutOfOrder:
+		return "out_of_order"
+	}
+	return "unknown"
+}
+
+// checkEventStatuses maps check events to the status they imply.
+var checkEventStatuses = map[WebhookEvent]CheckStatus{
+	WebhookEventCheckStarted:       CheckStatusInProgress,
+	WebhookEventCheckReopened:      CheckStatusReopened,
+	WebhookEventCheckWithdrawn:     CheckStatusWithdrawn,
+	WebhookEventCheckCompleted:     CheckStatusComplete,
+	WebhookEventCheckFormOpened:    CheckStatusAwaitingApplicant,
+	WebhookEventCheckFormCompleted: CheckStatusInProgress,
+}
+
+// reportEventStatuses maps report events to the status they imply.
+var reportEventStatuses = map[WebhookEvent]ReportStatus{
+	WebhookEventReportWithdrawn:        ReportStatusWithdrawn,
+	WebhookEventReportResumed:          ReportStatusAwaitingData,
+	WebhookEventReportCancelled:        ReportStatusCancelled,
+	WebhookEventReportAwaitingApproval: ReportStatusAwaitingApproval,
+	WebhookEventReportInitiated:        ReportStatusAwaitingData,
+	WebhookEventReportCompleted:        ReportStatusComplete,
+}
+
+// EventSequencer tracks the status of checks and reports from webhook
+// events to detect events which arrive out of order. Webhook payloads for
+// reports do not reference their check, so reports must be linked to their
+// check with LinkReport for check completion to be taken into account.
+// It is safe for concurrent use.
+type EventSequencer struct {
+	mu          sync.Mutex
+	checks      map[string]CheckStatus
+	reports     map[string]ReportStatus
+	reportCheck map[string]string
+}
+
+// NewEventSequencer creates a new event sequencer.
+func NewEventSequencer() *EventSequencer {
+	return &EventSequencer{
+		checks:      make(map[string]CheckStatus),
+		reports:     make(map[string]ReportStatus),
+		reportCheck: make(map[string]string),
+	}
+}
+
+// LinkReport records that a report belongs to a check.
+func (s *EventSequencer) LinkReport(reportID, checkID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reportCheck[reportID] = checkID
+}
+
+// Observe records the webhook event and reports whether it arrived in order.
+// Out of order and duplicate events do not change the tracked status.
+// Events for unknown resource types or actions are always in order.
+func (s *EventSequencer) Observe(wr *WebhookRequest) EventOrder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event := wr.Payload.Action
+	id := wr.Payload.Object.ID
+
+	if to, ok := checkEventStatuses[event]; ok {
+		from, seen := s.checks[id]
+		order := EventInOrder
+		switch {
+		case !seen:
+		case from == to:
+			order = EventDuplicate
+		case !CanTransitionCheck(from, to):
+			order = EventOutOfOrder
+		}
+		if order == EventInOrder {
+			s.checks[id] = to
+		}
+		return order
+	}
+
+	if to, ok := reportEventStatuses[event]; ok {
+		from, seen := s.reports[id]
+		order := EventInOrder
+		switch {
+		case seen && from == to:
+			order = EventDuplicate
+		case seen && !CanTransitionReport(from, to):
+			order = EventOutOfOrder
+		case s.checks[s.reportCheck[id]].IsTerminal():
+			// A report can't change once its check has finished
+			order = EventOutOfOrder
+		}
+		if order == EventInOrder {
+			s.reports[id] = to
+		}
+		return order
+	}
+
+	return EventInOrder
+}
@@ -0,0 +1,56 @@
+package onfido
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckStatus_CanTransition(t *testing.T) {
+	assert.True(t, CheckStatusInProgress.CanTransition(CheckStatusAwaitingApplicant))
+	assert.True(t, CheckStatusAwaitingApplicant.CanTransition(CheckStatusComplete))
+	assert.True(t, CheckStatusPaused.CanTransition(CheckStatusReopened))
+	assert.True(t, CheckStatusComplete.CanTransition(CheckStatusReopened))
+	assert.False(t, CheckStatusComplete.CanTransition(CheckStatusInProgress))
+	assert.False(t, CheckStatusWithdrawn.CanTransition(CheckStatusInProgress))
+	assert.False(t, CanTransitionCheck("unknown", CheckStatusComplete))
+}
+
+func TestCheckStatus_IsTerminal(t *testing.T) {
+	assert.True(t, CheckStatusComplete.IsTerminal())
+	assert.True(t, CheckStatusWithdrawn.IsTerminal())
+	assert.False(t, CheckStatusPaused.IsTerminal())
+	assert.False(t, CheckStatus("unknown").IsKnown())
+}
+
+func TestReportStatus_CanTransition(t *testing.T) {
+	assert.True(t, ReportStatusAwaitingData.CanTransition(ReportStatusComplete))
+	assert.True(t, ReportStatusPaused.CanTransition(ReportStatusAwaitingData))
+	assert.False(t, ReportStatusComplete.CanTransition(ReportStatusAwaitingData))
+	assert.True(t, ReportStatusCancelled.IsTerminal())
+	assert.False(t, ReportStatusAwaitingApproval.IsTerminal())
+}
+
+func webhookEvent(resourceType WebhookResourceType, action WebhookEvent, id string) *WebhookRequest {
+	var wr WebhookRequest
+	wr.Payload.ResourceType = resourceType
+	wr.Payload.Action = action
+	wr.Payload.Object.ID = id
+	return &wr
+}
+
+func TestEventSequencer_Observe(t *testing.T) {
+	s := NewEventSequencer()
+	s.LinkReport("report-1", "check-1")
+	s.LinkReport("report-2", "check-1")
+
+	assert.Equal(t, EventInOrder, s.Observe(webhookEvent("check", WebhookEventCheckStarted, "check-1")))
+	assert.Equal(t, EventInOrder, s.Observe(webhookEvent("report", WebhookEventReportCompleted, "report-1")))
+	assert.Equal(t, EventDuplicate, s.Observe(webhookEvent("report", WebhookEventReportCompleted, "report-1")))
+	assert.Equal(t, EventInOrder, s.Observe(webhookEvent("check", WebhookEventCheckCompleted, "check-1")))
+	assert.Equal(t, EventOutOfOrder, s.Observe(webhookEvent("report", WebhookEventReportCompleted, "report-2")))
+	assert.Equal(t, EventOutOfOrder, s.Observe(webhookEvent("check", WebhookEventCheckStarted, "check-1")))
+	assert.Equal(t, EventInOrder, s.Observe(webhookEvent("check", WebhookEventCheckReopened, "check-1")))
+	assert.Equal(t, EventInOrder, s.Observe(webhookEvent("report", WebhookEventReportCompleted, "report-2")))
+	assert.Equal(t, EventInOrder, s.Observe(webhookEvent("applicant", "applicant.created", "applicant-1")))
+}
@@ -2,17 +2,23 @@ package onfido
 
 import (
 	"crypto/hmac"
+	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"hash"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 )
 
 type Webhook interface {
 	ValidateSignature(body []byte, signature string) error
+	MatchSignature(body []byte, signature string) (int, error)
 	ParseFromRequest(req *http.Request) (*WebhookRequest, error)
 }
 
@@ -20,66 +26,301 @@ var _ Webhook = &webhook{}
 
 // Constants
 const (
-	WebhookSignatureHeader = "X-Sha2-Signature"
-	WebhookTokenEnv        = "ONFIDO_WEBHOOK_TOKEN"
+	WebhookSignatureHeader       = "X-Sha2-Signature"
+	WebhookLegacySignatureHeader = "X-Signature"
+	WebhookTokenEnv              = "ONFIDO_WEBHOOK_TOKEN"
+	WebhookTokensEnv             = "ONFIDO_WEBHOOK_TOKENS"
 )
 
 // Webhook errors
 var (
 	ErrInvalidWebhookSignature = errors.New("invalid request, payload hash doesn't match signature")
+	ErrMissingWebhookSignature = errors.New("invalid request, missing signature")
 	ErrMissingWebhookToken     = errors.New("webhook token not found in environmental variable")
+	ErrNoWebhookTokens         = errors.New("no webhook tokens configured")
 )
 
+// WebhookSignatureError is returned when a request's signature is missing
+// or doesn't match, naming the header which failed. It matches
+// ErrMissingWebhookSignature or ErrInvalidWebhookSignature with errors.Is.
+type WebhookSignatureError struct {
+	Header  string
+	Missing bool
+}
+
+func (e *WebhookSignatureError) Error() string {
+	if e.Missing {
+		return fmt.Sprintf("invalid request, missing %s header", e.Header)
+	}
+	return fmt.Sprintf("invalid request, payload hash doesn't match %s header", e.Header)
+}
+
+// Is reports whether target is the sentinel error matching e.
+func (e *WebhookSignatureError) Is(target error) bool {
+	if e.Missing {
+		return target == ErrMissingWebhookSignature
+	}
+	return target == ErrInvalidWebhookSignature
+}
+
+// WebhookSignatureScheme represents the signature headers a webhook is validated with
+type WebhookSignatureScheme int
+
+// Supported signature schemes
+const (
+	// WebhookSchemeSHA256 validates the HMAC-SHA256 X-Sha2-Signature header.
+	WebhookSchemeSHA256 WebhookSignatureScheme = iota
+	// WebhookSchemeSHA1 validates the legacy HMAC-SHA1 X-Signature header.
+	WebhookSchemeSHA1
+	// WebhookSchemeSHA256OrSHA1 validates X-Sha2-Signature if present, and
+	// falls back to X-Signature for proxies which only forward the latter.
+	WebhookSchemeSHA256OrSHA1
+	// WebhookSchemeSHA256AndSHA1 requires both headers to be present and valid.
+	WebhookSchemeSHA256AndSHA1
+)
+
+// TokenSource provides the webhook tokens which are currently valid. While
+// rotating tokens both the old and the new token should be returned.
+type TokenSource interface {
+	Tokens() []string
+}
+
+// StaticTokens is a TokenSource returning a fixed list of tokens
+type StaticTokens []string
+
+// Tokens implements TokenSource.
+func (t StaticTokens) Tokens() []string { return t }
+
 // Webhook represents a webhook handler
 type webhook struct {
 	Token                   string
+	TokenSource             TokenSource
+	Scheme                  WebhookSignatureScheme
 	SkipSignatureValidation bool
 }
 
+// WebhookResourceType represents the type of resource a webhook event refers to
+type WebhookResourceType string
+
+// Supported webhook resource types
+const (
+	WebhookResourceCheck            WebhookResourceType = "check"
+	WebhookResourceReport           WebhookResourceType = "report"
+	WebhookResourceDocument         WebhookResourceType = "document"
+	WebhookResourceWorkflowRun      WebhookResourceType = "workflow_run"
+	WebhookResourceWorkflowTask     WebhookResourceType = "workflow_task"
+	WebhookResourceWatchlistMonitor WebhookResourceType = "watchlist_monitor"
+	WebhookResourceAuditLog         WebhookResourceType = "audit_log"
+)
+
 // WebhookRequest represents an incoming webhook request from Onfido
 type WebhookRequest struct {
-	Payload struct {
-		ResourceType string `json:"resource_type"`
-		Action       string `json:"action"`
-		Object       struct {
-			ID          string `json:"id"`
-			Status      string `json:"status"`
-			CompletedAt string `json:"completed_at_iso8601"`
-			Href        string `json:"href"`
-		} `json:"object"`
-	} `json:"payload"`
+	Payload WebhookPayload `json:"payload"`
+
+	// TokenIndex is the index of the token which matched the request's
+	// signature, -1 if signature validation was skipped.
+	TokenIndex int `json:"-"`
+
+	// Raw is the request body the webhook was decoded from, so fields not
+	// modelled here can still be read.
+	Raw json.RawMessage `json:"-"`
+}
+
+// WebhookPayload represents the payload of a webhook request
+type WebhookPayload struct {
+	ResourceType WebhookResourceType `json:"resource_type"`
+	Action       WebhookEvent        `json:"action"`
+	Object       WebhookObject       `json:"object"`
+}
+
+// WebhookObject represents the resource a webhook event refers to
+type WebhookObject struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	CompletedAt string `json:"completed_at_iso8601"`
+	Href        string `json:"href"`
+
+	// CompletedAtTime is CompletedAt parsed as a time, zero if it was
+	// missing or not a valid ISO 8601 timestamp.
+	CompletedAtTime time.Time `json:"-"`
+
+	// Raw is the object's JSON, including any fields not modelled here.
+	Raw json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the object, parsing the completion time and retaining the raw JSON.
+func (o *WebhookObject) UnmarshalJSON(data []byte) error {
+	type object WebhookObject
+	var v object
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*o = WebhookObject(v)
+	o.Raw = append(json.RawMessage(nil), data...)
+	if o.CompletedAt != "" {
+		if t, err := time.Parse(time.RFC3339, o.CompletedAt); err == nil {
+			o.CompletedAtTime = t
+		}
+	}
+	return nil
 }
 
 // NewWebhookFromEnv creates a new webhook handler using
-// configuration from environment variables.
+// configuration from environment variables. ONFIDO_WEBHOOK_TOKENS may hold
+// a comma separated list of tokens, otherwise ONFIDO_WEBHOOK_TOKEN is used.
 func NewWebhookFromEnv() (Webhook, error) {
-	token := os.Getenv(WebhookTokenEnv)
-	if token == "" {
+	var tokens []string
+	for _, t := range strings.Split(os.Getenv(WebhookTokensEnv), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	if len(tokens) == 0 {
+		if token := os.Getenv(WebhookTokenEnv); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	if len(tokens) == 0 {
 		return nil, ErrMissingWebhookToken
 	}
-	return NewWebhook(token), nil
+	return NewWebhook(tokens...), nil
+}
+
+// NewWebhook creates a new webhook handler. Multiple tokens can be given
+// while rotating tokens, a signature matching any of them is valid. Empty
+// tokens are ignored, and without any token every signature is rejected
+// with ErrNoWebhookTokens.
+func NewWebhook(tokens ...string) Webhook {
+	wh := &webhook{}
+	if len(tokens) > 0 {
+		wh.Token = tokens[0]
+	}
+	if len(tokens) > 1 {
+		wh.TokenSource = StaticTokens(tokens)
+	}
+	return wh
+}
+
+// NewWebhookWithScheme creates a new webhook handler validating signatures
+// with the given scheme.
+func NewWebhookWithScheme(scheme WebhookSignatureScheme, tokens ...string) Webhook {
+	wh := NewWebhook(tokens...).(*webhook)
+	wh.Scheme = scheme
+	return wh
+}
+
+// NewWebhookWithTokenSource creates a new webhook handler validating
+// signatures against the tokens provided by ts.
+func NewWebhookWithTokenSource(ts TokenSource) Webhook {
+	return &webhook{TokenSource: ts}
 }
 
-// NewWebhook creates a new webhook handler
-func NewWebhook(token string) Webhook {
-	return &webhook{
-		Token: token,
+func (wh *webhook) tokens() []string {
+	if wh.TokenSource != nil {
+		return wh.TokenSource.Tokens()
 	}
+	return []string{wh.Token}
 }
 
 // ValidateSignature validates the request body against the signature header.
 func (wh *webhook) ValidateSignature(body []byte, signature string) error {
-	mac := hmac.New(sha256.New, []byte(wh.Token))
-	if _, err := mac.Write(body); err != nil {
-		return err
+	_, err := wh.MatchSignature(body, signature)
+	return err
+}
+
+// MatchSignature validates the request body against the signature header
+// and returns the index of the token which matched. The signature is
+// checked as the header the webhook's scheme validates would be: with
+// WebhookSchemeSHA256OrSHA1 a 40 character signature is taken to be the
+// legacy HMAC-SHA1 one, and with WebhookSchemeSHA256AndSHA1 a single
+// signature is never enough, use ParseFromRequest instead. Every token is
+// compared in constant time, regardless of which one matches. Empty tokens
+// never match, ErrNoWebhookTokens is returned if there are no others.
+func (wh *webhook) MatchSignature(body []byte, signature string) (int, error) {
+	header := WebhookSignatureHeader
+	switch wh.Scheme {
+	case WebhookSchemeSHA1:
+		header = WebhookLegacySignatureHeader
+	case WebhookSchemeSHA256OrSHA1:
+		if len(signature) == 2*sha1.Size {
+			header = WebhookLegacySignatureHeader
+		}
 	}
+	req := &http.Request{Header: make(http.Header)}
+	req.Header.Set(header, signature)
+	return wh.verify(req, body)
+}
 
+func (wh *webhook) matchSignature(h func() hash.Hash, body []byte, signature string) (int, error) {
 	sig, err := hex.DecodeString(signature)
-	if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
-		return ErrInvalidWebhookSignature
+	if err != nil {
+		return -1, ErrInvalidWebhookSignature
 	}
 
-	return nil
+	matched, configured := -1, false
+	for i, token := range wh.tokens() {
+		if token == "" {
+			continue
+		}
+		configured = true
+		mac := hmac.New(h, []byte(token))
+		if _, err := mac.Write(body); err != nil {
+			return -1, err
+		}
+		if hmac.Equal(sig, mac.Sum(nil)) && matched < 0 {
+			matched = i
+		}
+	}
+	if !configured {
+		return -1, ErrNoWebhookTokens
+	}
+	if matched < 0 {
+		return -1, ErrInvalidWebhookSignature
+	}
+
+	return matched, nil
+}
+
+// matchHeader validates the body against the signature in the given
+// header, returning a WebhookSignatureError naming the header on failure.
+func (wh *webhook) matchHeader(req *http.Request, header string, h func() hash.Hash, body []byte) (int, error) {
+	signature := req.Header.Get(header)
+	if signature == "" {
+		return -1, &WebhookSignatureError{Header: header, Missing: true}
+	}
+	i, err := wh.matchSignature(h, body, signature)
+	if err == ErrInvalidWebhookSignature {
+		return -1, &WebhookSignatureError{Header: header}
+	}
+	return i, err
+}
+
+// verify validates the request's signature headers according to the
+// webhook's scheme and returns the index of the matching token.
+func (wh *webhook) verify(req *http.Request, body []byte) (int, error) {
+	switch wh.Scheme {
+	case WebhookSchemeSHA1:
+		return wh.matchHeader(req, WebhookLegacySignatureHeader, sha1.New, body)
+	case WebhookSchemeSHA256OrSHA1:
+		if req.Header.Get(WebhookSignatureHeader) != "" {
+			return wh.matchHeader(req, WebhookSignatureHeader, sha256.New, body)
+		}
+		if req.Header.Get(WebhookLegacySignatureHeader) != "" {
+			return wh.matchHeader(req, WebhookLegacySignatureHeader, sha1.New, body)
+		}
+		return -1, &WebhookSignatureError{Header: WebhookSignatureHeader + " or " + WebhookLegacySignatureHeader, Missing: true}
+	case WebhookSchemeSHA256AndSHA1:
+		i, err := wh.matchHeader(req, WebhookSignatureHeader, sha256.New, body)
+		if err != nil {
+			return -1, err
+		}
+		if _, err := wh.matchHeader(req, WebhookLegacySignatureHeader, sha1.New, body); err != nil {
+			return -1, err
+		}
+		return i, nil
+	default:
+		return wh.matchHeader(req, WebhookSignatureHeader, sha256.New, body)
+	}
 }
 
 // ParseFromRequest parses the webhook request body and returns
@@ -92,13 +333,10 @@ func (wh *webhook) ParseFromRequest(req *http.Request) (*WebhookRequest, error)
 		return nil, err
 	}
 
+	tokenIndex := -1
 	if !wh.SkipSignatureValidation {
-		signature := req.Header.Get(WebhookSignatureHeader)
-		if signature == "" {
-			return nil, errors.New("invalid request, missing signature")
-		}
-
-		if err := wh.ValidateSignature(body, signature); err != nil {
+		tokenIndex, err = wh.verify(req, body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -107,6 +345,8 @@ func (wh *webhook) ParseFromRequest(req *http.Request) (*WebhookRequest, error)
 	if err := json.Unmarshal(body, &wr); err != nil {
 		return nil, err
 	}
+	wr.TokenIndex = tokenIndex
+	wr.Raw = body
 
 	return &wr, nil
 }
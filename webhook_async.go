@@ -0,0 +1,164 @@
+package onfido
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Defaults for asynchronous webhook processing
+const (
+	DefaultWebhookMaxAttempts     = 5
+	DefaultWebhookRetryBackoff    = time.Second
+	DefaultWebhookMaxRetryBackoff = 5 * time.Minute
+)
+
+// Asynchronous webhook processing errors
+var (
+	ErrWebhookQueueNotConfigured = errors.New("webhook handler has no queue configured")
+	ErrWebhookHandlerClosed      = errors.New("webhook handler has been shut down")
+)
+
+// enqueue queues the event. Shutdown waits for events being queued, so
+// that none is queued after the workers have stopped.
+func (h *WebhookHandler) enqueue(ctx context.Context, wr *WebhookRequest) error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return ErrWebhookHandlerClosed
+	}
+	h.enqueuing.Add(1)
+	h.mu.Unlock()
+	defer h.enqueuing.Done()
+
+	body := []byte(wr.Raw)
+	if len(body) == 0 {
+		var err error
+		if body, err = json.Marshal(wr); err != nil {
+			return err
+		}
+	}
+	item, err := NewWebhookQueueItem(body)
+	if err != nil {
+		return err
+	}
+	return h.opts.Queue.Enqueue(ctx, item)
+}
+
+// Start starts the given number of workers dispatching queued events.
+// Handlers are called with ctx. Start must only be called once.
+func (h *WebhookHandler) Start(ctx context.Context, workers int) error {
+	if h.opts.Queue == nil {
+		return ErrWebhookQueueNotConfigured
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	waitCtx, cancel := context.WithCancel(ctx)
+	h.mu.Lock()
+	h.stopWait = cancel
+	h.mu.Unlock()
+
+	for i := 0; i < workers; i++ {
+		h.workers.Add(1)
+		go func() {
+			defer h.workers.Done()
+			h.work(ctx, waitCtx)
+		}()
+	}
+	return nil
+}
+
+// Shutdown stops accepting new events and waits for the workers to drain
+// the events which are ready to be processed, including those still being
+// queued. Events waiting to be retried stay in the queue. It returns
+// ctx.Err() if ctx is done first.
+func (h *WebhookHandler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	stopWait := h.stopWait
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.enqueuing.Wait()
+		if stopWait != nil {
+			stopWait()
+		}
+		h.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// work processes queued events until waitCtx is done and no ready events remain.
+func (h *WebhookHandler) work(ctx, waitCtx context.Context) {
+	for {
+		item, err := h.opts.Queue.Dequeue(waitCtx)
+		if err != nil {
+			if waitCtx.Err() != nil {
+				return
+			}
+			h.logf("onfido: webhook dequeue failed: %v", err)
+			select {
+			case <-time.After(h.opts.RetryBackoff):
+			case <-waitCtx.Done():
+			}
+			continue
+		}
+		h.process(ctx, item)
+	}
+}
+
+// process dispatches a queued event, retrying it with backoff or dead
+// lettering it if it fails.
+func (h *WebhookHandler) process(ctx context.Context, item *WebhookQueueItem) {
+	var wr WebhookRequest
+	err := json.Unmarshal(item.Body, &wr)
+	if err == nil {
+		wr.Raw = []byte(item.Body)
+		wr.TokenIndex = -1
+		err = h.dispatch(ctx, &wr)
+	}
+	if err == nil {
+		if err := h.opts.Queue.Ack(ctx, item); err != nil {
+			h.logf("onfido: webhook ack failed: %v", err)
+		}
+		return
+	}
+
+	item.Attempts++
+	item.LastError = err.Error()
+	h.logf("onfido: webhook %s %s attempt %d failed: %v", wr.Payload.Action, wr.Payload.Object.ID, item.Attempts, err)
+
+	if item.Attempts >= h.opts.MaxAttempts {
+		if err := h.opts.Queue.DeadLetter(ctx, item); err != nil {
+			h.logf("onfido: webhook dead letter failed: %v", err)
+		}
+		return
+	}
+	retryAt := h.now().Add(h.retryBackoff(item.Attempts))
+	item.RetryAt = &retryAt
+	if err := h.opts.Queue.Nack(ctx, item); err != nil {
+		h.logf("onfido: webhook nack failed: %v", err)
+	}
+}
+
+// retryBackoff returns the delay before retrying after the given number of attempts.
+func (h *WebhookHandler) retryBackoff(attempts int) time.Duration {
+	d := h.opts.RetryBackoff
+	for i := 1; i < attempts && d < h.opts.MaxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > h.opts.MaxRetryBackoff {
+		d = h.opts.MaxRetryBackoff
+	}
+	return d
+}
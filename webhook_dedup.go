@@ -0,0 +1,284 @@
+package onfido
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebhookDedupStore records webhook events which have been handled so that
+// duplicate deliveries can be dropped.
+type WebhookDedupStore interface {
+	// MarkSeen records the key and reports whether it had already been recorded.
+	MarkSeen(ctx context.Context, key string) (bool, error)
+	// Forget removes the key, so that a retry of a failed event is handled.
+	Forget(ctx context.Context, key string) error
+	// SeenAt returns when the key was first recorded, and false if it isn't.
+	SeenAt(ctx context.Context, key string) (time.Time, bool, error)
+}
+
+// WebhookDedupKey returns the key identifying a webhook event, made up of
+// the resource ID, action and completion time. Events without a completion
+// time, such as report.resumed, use a hash of their body instead, so that
+// redeliveries are dropped but later occurrences of the action are not.
+func WebhookDedupKey(wr *WebhookRequest) string {
+	discriminator := wr.Payload.Object.CompletedAt
+	if discriminator == "" {
+		discriminator = webhookBodyHash(wr)
+	}
+	return strings.Join([]string{
+		wr.Payload.Object.ID,
+		string(wr.Payload.Action),
+		discriminator,
+	}, "|")
+}
+
+// webhookBodyHash returns the hex SHA-256 of the event's compacted body, or
+// of its payload if it wasn't decoded from a body.
+func webhookBodyHash(wr *WebhookRequest) string {
+	body := []byte(wr.Raw)
+	if len(body) == 0 {
+		var err error
+		if body, err = json.Marshal(wr.Payload); err != nil {
+			return ""
+		}
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, body); err == nil {
+		body = buf.Bytes()
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// webhookStatusKey returns the key recording when a check's status change
+// announced by action was last delivered, whether it was pushed or found by
+// a WebhookReconciler.
+func webhookStatusKey(id string, action WebhookEvent) string {
+	return strings.Join([]string{id, string(action), "status"}, "|")
+}
+
+// MemoryDedupStore is an in-memory WebhookDedupStore which remembers a
+// bounded number of keys, evicting the least recently seen.
+type MemoryDedupStore struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	keys  map[string]*list.Element
+}
+
+// memoryDedupEntry is a key remembered by a MemoryDedupStore.
+type memoryDedupEntry struct {
+	key    string
+	seenAt time.Time
+}
+
+var _ WebhookDedupStore = &MemoryDedupStore{}
+
+// NewMemoryDedupStore creates a new in-memory store remembering up to size keys.
+func NewMemoryDedupStore(size int) *MemoryDedupStore {
+	if size <= 0 {
+		size = 1
+	}
+	return &MemoryDedupStore{
+		size:  size,
+		order: list.New(),
+		keys:  make(map[string]*list.Element),
+	}
+}
+
+// MarkSeen implements WebhookDedupStore.
+func (s *MemoryDedupStore) MarkSeen(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.keys[key]; ok {
+		s.order.MoveToFront(el)
+		return true, nil
+	}
+
+	s.keys[key] = s.order.PushFront(&memoryDedupEntry{key: key, seenAt: time.Now()})
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.keys, oldest.Value.(*memoryDedupEntry).key)
+	}
+	return false, nil
+}
+
+// SeenAt implements WebhookDedupStore.
+func (s *MemoryDedupStore) SeenAt(_ context.Context, key string) (time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.keys[key]; ok {
+		return el.Value.(*memoryDedupEntry).seenAt, true, nil
+	}
+	return time.Time{}, false, nil
+}
+
+// Forget implements WebhookDedupStore.
+func (s *MemoryDedupStore) Forget(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.keys[key]; ok {
+		s.order.Remove(el)
+		delete(s.keys, key)
+	}
+	return nil
+}
+
+// SQLPlaceholder represents the bind parameter style of a SQL driver
+type SQLPlaceholder int
+
+// Supported placeholder styles
+const (
+	// SQLPlaceholderQuestion uses ? placeholders (MySQL, SQLite)
+	SQLPlaceholderQuestion SQLPlaceholder = iota
+	// SQLPlaceholderDollar uses $1 placeholders (PostgreSQL)
+	SQLPlaceholderDollar
+)
+
+// SQLConflictStyle represents how a SQL dialect skips inserting a duplicate key
+type SQLConflictStyle int
+
+// Supported conflict styles
+const (
+	// SQLConflictDoNothing uses INSERT ... ON CONFLICT DO NOTHING (PostgreSQL, SQLite)
+	SQLConflictDoNothing SQLConflictStyle = iota
+	// SQLConflictInsertIgnore uses INSERT IGNORE (MySQL)
+	SQLConflictInsertIgnore
+)
+
+// ErrInvalidSQLTable is returned when a SQL store's table name isn't a
+// plain, optionally schema qualified, identifier.
+var ErrInvalidSQLTable = errors.New("invalid sql table name")
+
+var sqlTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SQLDedupStore is a WebhookDedupStore backed by a database/sql table with
+// a dedup_key primary key and a seen_at timestamp column.
+type SQLDedupStore struct {
+	DB          *sql.DB
+	Table       string
+	Placeholder SQLPlaceholder
+	Conflict    SQLConflictStyle
+}
+
+var _ WebhookDedupStore = &SQLDedupStore{}
+
+// NewSQLDedupStore creates a new SQL store using the given table. Use
+// SQLConflictInsertIgnore as the store's Conflict style for MySQL.
+func NewSQLDedupStore(db *sql.DB, table string, placeholder SQLPlaceholder) *SQLDedupStore {
+	return &SQLDedupStore{DB: db, Table: table, Placeholder: placeholder}
+}
+
+// table returns the store's table name, checking it is safe to use in a query.
+func (s *SQLDedupStore) table() (string, error) {
+	if !sqlTableName.MatchString(s.Table) {
+		return "", ErrInvalidSQLTable
+	}
+	return s.Table, nil
+}
+
+// CreateTable creates the store's table if it doesn't exist.
+func (s *SQLDedupStore) CreateTable(ctx context.Context) error {
+	table, err := s.table()
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+table+
+		" (dedup_key VARCHAR(255) NOT NULL PRIMARY KEY, seen_at TIMESTAMP NOT NULL)")
+	return err
+}
+
+// MarkSeen implements WebhookDedupStore. The key is inserted in a single
+// statement which skips existing keys, so concurrent duplicates are
+// recorded once and all but one report the key as seen.
+func (s *SQLDedupStore) MarkSeen(ctx context.Context, key string) (bool, error) {
+	table, err := s.table()
+	if err != nil {
+		return false, err
+	}
+
+	query := "INSERT INTO " + table + " (dedup_key, seen_at) VALUES (?, ?) ON CONFLICT DO NOTHING"
+	if s.Conflict == SQLConflictInsertIgnore {
+		query = "INSERT IGNORE INTO " + table + " (dedup_key, seen_at) VALUES (?, ?)"
+	}
+	res, err := s.DB.ExecContext(ctx, s.bind(query), key, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 0, nil
+}
+
+// Forget implements WebhookDedupStore.
+func (s *SQLDedupStore) Forget(ctx context.Context, key string) error {
+	table, err := s.table()
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.ExecContext(ctx, s.bind("DELETE FROM "+table+" WHERE dedup_key = ?"), key)
+	return err
+}
+
+// SeenAt implements WebhookDedupStore.
+func (s *SQLDedupStore) SeenAt(ctx context.Context, key string) (time.Time, bool, error) {
+	table, err := s.table()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	var seenAt time.Time
+	err = s.DB.QueryRowContext(ctx, s.bind("SELECT seen_at FROM "+table+" WHERE dedup_key = ?"), key).Scan(&seenAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return seenAt, true, nil
+}
+
+// Prune removes keys seen before the given time.
+func (s *SQLDedupStore) Prune(ctx context.Context, before time.Time) error {
+	table, err := s.table()
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.ExecContext(ctx, s.bind("DELETE FROM "+table+" WHERE seen_at < ?"), before.UTC())
+	return err
+}
+
+// bind rewrites ? placeholders into the store's placeholder style.
+func (s *SQLDedupStore) bind(query string) string {
+	if s.Placeholder != SQLPlaceholderDollar {
+		return query
+	}
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,276 @@
+package onfido
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryDedupStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryDedupStore(2)
+
+	seen, _ := s.MarkSeen(ctx, "a")
+	assert.False(t, seen)
+	seen, _ = s.MarkSeen(ctx, "a")
+	assert.True(t, seen)
+
+	s.MarkSeen(ctx, "b")
+	s.MarkSeen(ctx, "c") // evicts a
+	seen, _ = s.MarkSeen(ctx, "a")
+	assert.False(t, seen)
+
+	assert.NoError(t, s.Forget(ctx, "a"))
+	seen, _ = s.MarkSeen(ctx, "a")
+	assert.False(t, seen)
+
+	seenAt, ok, err := s.SeenAt(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now(), seenAt, time.Second)
+	_, ok, err = s.SeenAt(ctx, "b")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestWebhookHandler_DropsDuplicates(t *testing.T) {
+	h := newTestWebhookHandler()
+	h.opts.DedupStore = NewMemoryDedupStore(10)
+
+	calls := 0
+	fail := true
+	h.OnCheckCompleted(func(ctx context.Context, wr *WebhookRequest) error {
+		calls++
+		if fail {
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	})
+
+	sig := signWebhookBody(t, "abc123", []byte(testWebhookBody))
+	// A failed event is forgotten so Onfido's retry is handled
+	assert.Equal(t, http.StatusInternalServerError, serveWebhook(t, h, testWebhookBody, sig).Code)
+	fail = false
+	assert.Equal(t, http.StatusOK, serveWebhook(t, h, testWebhookBody, sig).Code)
+	assert.Equal(t, http.StatusOK, serveWebhook(t, h, testWebhookBody, sig).Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestWebhookHandler_DropsDuplicates_WithoutCompletionTime(t *testing.T) {
+	h := newTestWebhookHandler()
+	h.opts.DedupStore = NewMemoryDedupStore(10)
+
+	calls := 0
+	h.On(WebhookEventReportResumed, func(ctx context.Context, wr *WebhookRequest) error {
+		calls++
+		return nil
+	})
+
+	first := `{"payload": {"resource_type": "report", "action": "report.resumed", "object": {"id": "r1", "status": "awaiting_data", "href": "/v3.1/reports/r1"}}}`
+	second := `{"payload": {"resource_type": "report", "action": "report.resumed", "object": {"id": "r1", "status": "in_progress", "href": "/v3.1/reports/r1"}}}`
+	for _, body := range []string{first, first, second, second} {
+		assert.Equal(t, http.StatusOK, serveWebhook(t, h, body, signWebhookBody(t, "abc123", []byte(body))).Code)
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestWebhookDedupKey(t *testing.T) {
+	completed := &WebhookRequest{Payload: WebhookPayload{
+		Action: WebhookEventCheckCompleted,
+		Object: WebhookObject{ID: "c1", CompletedAt: "2020-05-12T10:37:04Z"},
+	}}
+	assert.Equal(t, "c1|check.completed|2020-05-12T10:37:04Z", WebhookDedupKey(completed))
+
+	// Whitespace in the body doesn't change the key
+	a := &WebhookRequest{Payload: WebhookPayload{Action: WebhookEventReportResumed, Object: WebhookObject{ID: "r1"}}, Raw: []byte(`{"payload": {"object": {"id": "r1"}}}`)}
+	b := &WebhookRequest{Payload: WebhookPayload{Action: WebhookEventReportResumed, Object: WebhookObject{ID: "r1"}}, Raw: []byte(`{"payload":{"object":{"id":"r1"}}}`)}
+	assert.Equal(t, WebhookDedupKey(a), WebhookDedupKey(b))
+	assert.True(t, strings.HasPrefix(WebhookDedupKey(a), "r1|report.resumed|"))
+	b.Raw = []byte(`{"payload":{"object":{"id":"r1","status":"in_progress"}}}`)
+	assert.NotEqual(t, WebhookDedupKey(a), WebhookDedupKey(b))
+}
+
+func TestWebhookHandler_MaxEventAge(t *testing.T) {
+	h := newTestWebhookHandler()
+	h.opts.MaxEventAge = time.Hour
+	h.now = func() time.Time { return time.Date(2020, 5, 12, 12, 0, 0, 0, time.UTC) }
+
+	fresh := `{"payload": {"action": "check.completed", "object": {"id": "c", "completed_at_iso8601": "2020-05-12T11:30:00Z"}}}`
+	stale := `{"payload": {"action": "check.completed", "object": {"id": "c", "completed_at_iso8601": "2020-05-12T10:30:00Z"}}}`
+	assert.Equal(t, http.StatusOK, serveWebhook(t, h, fresh, signWebhookBody(t, "abc123", []byte(fresh))).Code)
+	assert.Equal(t, http.StatusBadRequest, serveWebhook(t, h, stale, signWebhookBody(t, "abc123", []byte(stale))).Code)
+}
+
+func TestSQLDedupStore(t *testing.T) {
+	db := sql.OpenDB(&fakeDedupConnector{keys: make(map[string]time.Time)})
+	defer db.Close()
+
+	ctx := context.Background()
+	s := NewSQLDedupStore(db, "onfido_webhooks", SQLPlaceholderQuestion)
+	assert.NoError(t, s.CreateTable(ctx))
+
+	seen, err := s.MarkSeen(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, seen)
+	seen, err = s.MarkSeen(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, seen)
+
+	assert.NoError(t, s.Forget(ctx, "a"))
+	seen, err = s.MarkSeen(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, seen)
+
+	seenAt, ok, err := s.SeenAt(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now(), seenAt, time.Second)
+	_, ok, err = s.SeenAt(ctx, "b")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestSQLDedupStore_ConcurrentDuplicates(t *testing.T) {
+	db := sql.OpenDB(&fakeDedupConnector{keys: make(map[string]time.Time)})
+	defer db.Close()
+
+	s := NewSQLDedupStore(db, "onfido_webhooks", SQLPlaceholderQuestion)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	unseen := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			seen, err := s.MarkSeen(context.Background(), "a")
+			assert.NoError(t, err)
+			if !seen {
+				mu.Lock()
+				unseen++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, unseen)
+}
+
+func TestSQLDedupStore_ConflictStyles(t *testing.T) {
+	c := &fakeDedupConnector{keys: make(map[string]time.Time)}
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	ctx := context.Background()
+	s := NewSQLDedupStore(db, "public.onfido_webhooks", SQLPlaceholderDollar)
+	_, err := s.MarkSeen(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO public.onfido_webhooks (dedup_key, seen_at) VALUES ($1, $2) ON CONFLICT DO NOTHING", c.lastQuery)
+
+	s = NewSQLDedupStore(db, "onfido_webhooks", SQLPlaceholderQuestion)
+	s.Conflict = SQLConflictInsertIgnore
+	_, err = s.MarkSeen(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT IGNORE INTO onfido_webhooks (dedup_key, seen_at) VALUES (?, ?)", c.lastQuery)
+}
+
+func TestSQLDedupStore_InvalidTable(t *testing.T) {
+	c := &fakeDedupConnector{keys: make(map[string]time.Time)}
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	ctx := context.Background()
+	s := NewSQLDedupStore(db, "webhooks; DROP TABLE users", SQLPlaceholderQuestion)
+	assert.Equal(t, ErrInvalidSQLTable, s.CreateTable(ctx))
+	_, err := s.MarkSeen(ctx, "a")
+	assert.Equal(t, ErrInvalidSQLTable, err)
+	assert.Equal(t, ErrInvalidSQLTable, s.Forget(ctx, "a"))
+	_, _, err = s.SeenAt(ctx, "a")
+	assert.Equal(t, ErrInvalidSQLTable, err)
+	assert.Equal(t, ErrInvalidSQLTable, s.Prune(ctx, time.Now()))
+	assert.Empty(t, c.lastQuery)
+}
+
+func TestSQLDedupStore_DollarPlaceholders(t *testing.T) {
+	s := NewSQLDedupStore(nil, "t", SQLPlaceholderDollar)
+	assert.Equal(t, "INSERT INTO t (dedup_key, seen_at) VALUES ($1, $2)", s.bind("INSERT INTO t (dedup_key, seen_at) VALUES (?, ?)"))
+}
+
+// fakeDedupConnector is a minimal database/sql driver understanding the
+// statements issued by SQLDedupStore.
+type fakeDedupConnector struct {
+	mu        sync.Mutex
+	keys      map[string]time.Time
+	lastQuery string
+}
+
+func (c *fakeDedupConnector) Connect(context.Context) (driver.Conn, error) { return &fakeDedupConn{c}, nil }
+func (c *fakeDedupConnector) Driver() driver.Driver                      { return nil }
+
+type fakeDedupConn struct{ c *fakeDedupConnector }
+
+func (c *fakeDedupConn) Prepare(query string) (driver.Stmt, error) { return &fakeDedupStmt{c.c, query}, nil }
+func (c *fakeDedupConn) Close() error                              { return nil }
+func (c *fakeDedupConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c *fakeDedupConn) Commit() error                             { return nil }
+func (c *fakeDedupConn) Rollback() error                           { return nil }
+
+type fakeDedupStmt struct {
+	c     *fakeDedupConnector
+	query string
+}
+
+func (s *fakeDedupStmt) Close() error  { return nil }
+func (s *fakeDedupStmt) NumInput() int { return -1 }
+
+func (s *fakeDedupStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+	s.c.lastQuery = s.query
+	switch {
+	case strings.HasPrefix(s.query, "INSERT"):
+		if _, ok := s.c.keys[args[0].(string)]; ok {
+			return driver.RowsAffected(0), nil
+		}
+		s.c.keys[args[0].(string)] = args[1].(time.Time)
+	case strings.HasPrefix(s.query, "DELETE") && strings.Contains(s.query, "dedup_key"):
+		delete(s.c.keys, args[0].(string))
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeDedupStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+	s.c.lastQuery = s.query
+	if !strings.HasPrefix(s.query, "SELECT seen_at") {
+		return nil, errors.New("unexpected query")
+	}
+	rows := &fakeDedupRows{}
+	if seenAt, ok := s.c.keys[args[0].(string)]; ok {
+		rows.values = append(rows.values, seenAt)
+	}
+	return rows, nil
+}
+
+type fakeDedupRows struct{ values []time.Time }
+
+func (r *fakeDedupRows) Columns() []string { return []string{"seen_at"} }
+func (r *fakeDedupRows) Close() error      { return nil }
+
+func (r *fakeDedupRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
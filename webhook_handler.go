@@ -0,0 +1,333 @@
+package onfido
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// DefaultWebhookMaxBodyBytes is the default maximum size of a webhook request body.
+const DefaultWebhookMaxBodyBytes = 1 << 20
+
+// DefaultWebhookReconcileWindow is the default time a check's status change
+// is remembered, to drop the same change arriving by push and by polling.
+const DefaultWebhookReconcileWindow = 24 * time.Hour
+
+// WebhookHandlerFunc handles a validated webhook event. Returning an error
+// responds with a 500 so that Onfido retries the event.
+type WebhookHandlerFunc func(ctx context.Context, wr *WebhookRequest) error
+
+// WebhookHandlerOptions configures a WebhookHandler.
+type WebhookHandlerOptions struct {
+	// MaxBodyBytes limits the size of request bodies, DefaultWebhookMaxBodyBytes if zero.
+	MaxBodyBytes int64
+	// ErrorLog logs handler errors and panics, the standard logger if nil.
+	ErrorLog *log.Logger
+	// DedupStore, if set, is used to drop duplicate deliveries of an event.
+	DedupStore WebhookDedupStore
+	// MaxEventAge, if set, rejects events completed longer ago than this,
+	// protecting against replays of old signed bodies. Events without a
+	// completion time are not checked.
+	MaxEventAge time.Duration
+	// ReconcileWindow is how long a check's status change is remembered
+	// once delivered, so that a WebhookReconciler's event for the change
+	// and its pushed event are dispatched once between them,
+	// DefaultWebhookReconcileWindow if zero. It requires a DedupStore.
+	ReconcileWindow time.Duration
+
+	// Queue, if set, enables asynchronous processing: requests are
+	// acknowledged once validated and queued, and dispatched by the workers
+	// started with Start.
+	Queue WebhookQueue
+	// MaxAttempts is the number of times a queued event is dispatched before
+	// being dead lettered, DefaultWebhookMaxAttempts if zero.
+	MaxAttempts int
+	// RetryBackoff is the delay before the first retry of a queued event,
+	// doubling with each attempt, DefaultWebhookRetryBackoff if zero.
+	RetryBackoff time.Duration
+	// MaxRetryBackoff caps the delay between retries, DefaultWebhookMaxRetryBackoff if zero.
+	MaxRetryBackoff time.Duration
+}
+
+// WebhookHandler is an http.Handler which validates webhook requests and
+// routes them to the handler registered for their event. Handlers must be
+// registered before the WebhookHandler starts serving requests.
+type WebhookHandler struct {
+	wh       Webhook
+	opts     WebhookHandlerOptions
+	handlers map[WebhookEvent]WebhookHandlerFunc
+	fallback WebhookHandlerFunc
+	now      func() time.Time
+
+	// asynchronous processing state, see Start and Shutdown
+	mu        sync.Mutex
+	closed    bool
+	stopWait  context.CancelFunc
+	workers   sync.WaitGroup
+	enqueuing sync.WaitGroup
+}
+
+var _ http.Handler = &WebhookHandler{}
+
+// NewWebhookHandler creates a new webhook handler which validates requests with wh.
+func NewWebhookHandler(wh Webhook, opts WebhookHandlerOptions) *WebhookHandler {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultWebhookMaxBodyBytes
+	}
+	if opts.ReconcileWindow <= 0 {
+		opts.ReconcileWindow = DefaultWebhookReconcileWindow
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultWebhookMaxAttempts
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = DefaultWebhookRetryBackoff
+	}
+	if opts.MaxRetryBackoff <= 0 {
+		opts.MaxRetryBackoff = DefaultWebhookMaxRetryBackoff
+	}
+	return &WebhookHandler{
+		wh:       wh,
+		opts:     opts,
+		handlers: make(map[WebhookEvent]WebhookHandlerFunc),
+		now:      time.Now,
+	}
+}
+
+// On registers fn to handle the given event.
+func (h *WebhookHandler) On(event WebhookEvent, fn WebhookHandlerFunc) {
+	h.handlers[event] = fn
+}
+
+// OnCheckCompleted registers fn to handle check.completed events.
+func (h *WebhookHandler) OnCheckCompleted(fn WebhookHandlerFunc) {
+	h.On(WebhookEventCheckCompleted, fn)
+}
+
+// OnReportCompleted registers fn to handle report.completed events.
+func (h *WebhookHandler) OnReportCompleted(fn WebhookHandlerFunc) {
+	h.On(WebhookEventReportCompleted, fn)
+}
+
+// Fallback registers fn to handle events without a registered handler.
+// Without a fallback such events are acknowledged and ignored.
+func (h *WebhookHandler) Fallback(fn WebhookHandlerFunc) {
+	h.fallback = fn
+}
+
+// ServeHTTP validates and parses the webhook request and dispatches it.
+// It responds with 400 if the request is invalid or the signature doesn't
+// match, 413 if the body is too large, and 500 if the handler fails or panics.
+// Duplicate events are acknowledged without being dispatched, and events
+// older than MaxEventAge are rejected with a 400. In asynchronous mode the
+// handler responds with 202 once the event is queued, and with 503 after
+// Shutdown.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, h.opts.MaxBodyBytes+1))
+	req.Body.Close()
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > h.opts.MaxBodyBytes {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	wr, err := h.wh.ParseFromRequest(req)
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.Is(err, ErrInvalidWebhookSignature), errors.Is(err, ErrMissingWebhookSignature):
+			http.Error(w, "invalid signature", http.StatusBadRequest)
+		case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+		default:
+			h.logf("onfido: webhook parse failed: %v", err)
+			http.Error(w, "error occurred", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if h.opts.MaxEventAge > 0 {
+		completed := wr.Payload.Object.CompletedAtTime
+		if !completed.IsZero() && h.now().Sub(completed) > h.opts.MaxEventAge {
+			http.Error(w, "stale event", http.StatusBadRequest)
+			return
+		}
+	}
+
+	code, err := h.deliver(req.Context(), wr)
+	if err != nil {
+		http.Error(w, "error occurred", code)
+		return
+	}
+	w.WriteHeader(code)
+}
+
+// Deliver passes a validated event through the same path as requests
+// served by the handler: it is dropped if it was already delivered, then
+// queued or dispatched. It is used to inject events which were not
+// received as requests, such as those found by a WebhookReconciler.
+func (h *WebhookHandler) Deliver(ctx context.Context, wr *WebhookRequest) error {
+	_, err := h.deliver(ctx, wr)
+	return err
+}
+
+// deliver dedups and queues or dispatches the event, returning the status
+// code to respond with.
+func (h *WebhookHandler) deliver(ctx context.Context, wr *WebhookRequest) (int, error) {
+	var keys []string
+	if h.opts.DedupStore != nil {
+		// A WebhookReconciler's events have no completion time, so only
+		// their status change identifies them
+		if !isReconciledWebhook(wr) {
+			key := WebhookDedupKey(wr)
+			seen, err := h.opts.DedupStore.MarkSeen(ctx, key)
+			if err != nil {
+				h.logf("onfido: webhook dedup failed: %v", err)
+				return http.StatusInternalServerError, err
+			}
+			if seen {
+				return http.StatusOK, nil
+			}
+			keys = append(keys, key)
+		}
+		if wr.Payload.ResourceType == WebhookResourceCheck {
+			key, seen, err := h.markStatus(ctx, wr)
+			if err != nil {
+				h.logf("onfido: webhook dedup failed: %v", err)
+				h.forget(ctx, keys...)
+				return http.StatusInternalServerError, err
+			}
+			if seen {
+				return http.StatusOK, nil
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	if h.opts.Queue != nil {
+		if err := h.enqueue(ctx, wr); err != nil {
+			h.logf("onfido: webhook %s %s enqueue failed: %v", wr.Payload.Action, wr.Payload.Object.ID, err)
+			h.forget(ctx, keys...)
+			if err == ErrWebhookHandlerClosed {
+				return http.StatusServiceUnavailable, err
+			}
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusAccepted, nil
+	}
+
+	if err := h.dispatch(ctx, wr); err != nil {
+		h.logf("onfido: webhook %s %s failed: %v", wr.Payload.Action, wr.Payload.Object.ID, err)
+		h.forget(ctx, keys...)
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// isReconciledWebhook reports whether the event was emitted by a
+// WebhookReconciler rather than pushed, which always have a completion time.
+func isReconciledWebhook(wr *WebhookRequest) bool {
+	return wr.Payload.ResourceType == WebhookResourceCheck && wr.Payload.Object.CompletedAt == ""
+}
+
+// markStatus records that the check's status change announced by the event
+// is being delivered, returning the key it is recorded under. It reports
+// whether the change was already delivered by the other path within the
+// reconcile window: a reconciled change which was pushed or reconciled
+// before, or a pushed change completed before a WebhookReconciler found it.
+// A pushed event consumes the record of the reconciled change it matches,
+// as does any change of the check's status, so that the check completing
+// again after being reopened is dispatched.
+func (h *WebhookHandler) markStatus(ctx context.Context, wr *WebhookRequest) (string, bool, error) {
+	store := h.opts.DedupStore
+	key := webhookStatusKey(wr.Payload.Object.ID, wr.Payload.Action)
+
+	seenAt, ok, err := store.SeenAt(ctx, key)
+	if err != nil {
+		return "", false, err
+	}
+	if ok && h.now().Sub(seenAt) <= h.opts.ReconcileWindow {
+		if isReconciledWebhook(wr) {
+			return "", true, nil
+		}
+		// Onfido's completion times have second precision
+		if completed := wr.Payload.Object.CompletedAtTime; !completed.IsZero() && completed.Before(seenAt.Truncate(time.Second)) {
+			return "", true, store.Forget(ctx, key)
+		}
+	}
+
+	// Stores record when a key was first seen, so it is replaced to
+	// record this delivery
+	if err := store.Forget(ctx, key); err != nil {
+		return "", false, err
+	}
+	if _, err := store.MarkSeen(ctx, key); err != nil {
+		return "", false, err
+	}
+	for _, action := range checkStatusEvents {
+		if action == wr.Payload.Action {
+			continue
+		}
+		if err := store.Forget(ctx, webhookStatusKey(wr.Payload.Object.ID, action)); err != nil {
+			return "", false, err
+		}
+	}
+	return key, false, nil
+}
+
+// forget removes the event's keys from the dedup store so a retry is handled.
+func (h *WebhookHandler) forget(ctx context.Context, keys ...string) {
+	if h.opts.DedupStore == nil {
+		return
+	}
+	for _, key := range keys {
+		if err := h.opts.DedupStore.Forget(ctx, key); err != nil {
+			h.logf("onfido: webhook dedup forget failed: %v", err)
+		}
+	}
+}
+
+func (h *WebhookHandler) dispatch(ctx context.Context, wr *WebhookRequest) (err error) {
+	fn, ok := h.handlers[wr.Payload.Action]
+	if !ok {
+		fn = h.fallback
+	}
+	if fn == nil {
+		return nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return fn(ctx, wr)
+}
+
+func (h *WebhookHandler) logf(format string, args ...interface{}) {
+	if h.opts.ErrorLog != nil {
+		h.opts.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
@@ -0,0 +1,111 @@
+package onfido
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testWebhookBody = `{"payload": {"resource_type": "check", "action": "check.completed", "object": {"id": "check-1", "status": "complete"}}}`
+
+func newTestWebhookHandler() *WebhookHandler {
+	return NewWebhookHandler(NewWebhook("abc123"), WebhookHandlerOptions{
+		MaxBodyBytes: 1024,
+		ErrorLog:     log.New(ioutil.Discard, "", 0),
+	})
+}
+
+func serveWebhook(t *testing.T, h http.Handler, body string, signature string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(body))
+	if signature != "" {
+		req.Header.Set(WebhookSignatureHeader, signature)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWebhookHandler_DispatchesEvent(t *testing.T) {
+	h := newTestWebhookHandler()
+	var got *WebhookRequest
+	h.OnCheckCompleted(func(ctx context.Context, wr *WebhookRequest) error {
+		got = wr
+		return nil
+	})
+	h.OnReportCompleted(func(ctx context.Context, wr *WebhookRequest) error {
+		t.Fatal("unexpected report handler call")
+		return nil
+	})
+
+	rec := serveWebhook(t, h, testWebhookBody, signWebhookBody(t, "abc123", []byte(testWebhookBody)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.NotNil(t, got) {
+		assert.Equal(t, "check-1", got.Payload.Object.ID)
+	}
+}
+
+func TestWebhookHandler_Fallback(t *testing.T) {
+	h := newTestWebhookHandler()
+	rec := serveWebhook(t, h, testWebhookBody, signWebhookBody(t, "abc123", []byte(testWebhookBody)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	called := false
+	h.Fallback(func(ctx context.Context, wr *WebhookRequest) error {
+		called = true
+		return nil
+	})
+	rec = serveWebhook(t, h, testWebhookBody, signWebhookBody(t, "abc123", []byte(testWebhookBody)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, called)
+}
+
+func TestWebhookHandler_BadSignature(t *testing.T) {
+	h := newTestWebhookHandler()
+	assert.Equal(t, http.StatusBadRequest, serveWebhook(t, h, testWebhookBody, "deadbeef").Code)
+	assert.Equal(t, http.StatusBadRequest, serveWebhook(t, h, testWebhookBody, "").Code)
+}
+
+func TestWebhookHandler_InvalidPayload(t *testing.T) {
+	h := newTestWebhookHandler()
+	body := `{"payload": `
+	assert.Equal(t, http.StatusBadRequest, serveWebhook(t, h, body, signWebhookBody(t, "abc123", []byte(body))).Code)
+}
+
+func TestWebhookHandler_BodyTooLarge(t *testing.T) {
+	h := newTestWebhookHandler()
+	body := strings.Repeat("a", 1025)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serveWebhook(t, h, body, signWebhookBody(t, "abc123", []byte(body))).Code)
+}
+
+func TestWebhookHandler_HandlerError(t *testing.T) {
+	h := newTestWebhookHandler()
+	h.OnCheckCompleted(func(ctx context.Context, wr *WebhookRequest) error {
+		return errors.New("downstream unavailable")
+	})
+	rec := serveWebhook(t, h, testWebhookBody, signWebhookBody(t, "abc123", []byte(testWebhookBody)))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestWebhookHandler_HandlerPanic(t *testing.T) {
+	h := newTestWebhookHandler()
+	h.On(WebhookEventCheckCompleted, func(ctx context.Context, wr *WebhookRequest) error {
+		panic("boom")
+	})
+	rec := serveWebhook(t, h, testWebhookBody, signWebhookBody(t, "abc123", []byte(testWebhookBody)))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestWebhookHandler_MethodNotAllowed(t *testing.T) {
+	h := newTestWebhookHandler()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhook", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
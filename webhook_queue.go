@@ -0,0 +1,460 @@
+package onfido
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebhookQueueItem represents a webhook request waiting to be processed
+type WebhookQueueItem struct {
+	ID         string          `json:"id"`
+	Body       json.RawMessage `json:"body"`
+	Attempts   int             `json:"attempts"`
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+	RetryAt    *time.Time      `json:"retry_at,omitempty"`
+	LastError  string          `json:"last_error,omitempty"`
+}
+
+// WebhookQueue persists webhook requests for asynchronous processing.
+type WebhookQueue interface {
+	// Enqueue adds a new item to the queue.
+	Enqueue(ctx context.Context, item *WebhookQueueItem) error
+	// Dequeue claims the next item whose RetryAt has passed, blocking until
+	// one is ready. An item which is already ready is returned even if ctx
+	// is done, otherwise ctx.Err() is returned once ctx is done.
+	Dequeue(ctx context.Context) (*WebhookQueueItem, error)
+	// Ack removes a claimed item once it has been processed.
+	Ack(ctx context.Context, item *WebhookQueueItem) error
+	// Nack returns a claimed item to the queue to be retried at its RetryAt.
+	Nack(ctx context.Context, item *WebhookQueueItem) error
+	// DeadLetter moves a claimed item which can't be processed out of the queue.
+	DeadLetter(ctx context.Context, item *WebhookQueueItem) error
+}
+
+// NewWebhookQueueItem creates a new queue item for a webhook request body.
+func NewWebhookQueueItem(body []byte) (*WebhookQueueItem, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &WebhookQueueItem{
+		ID:         hex.EncodeToString(id),
+		Body:       append(json.RawMessage(nil), body...),
+		EnqueuedAt: time.Now().UTC(),
+	}, nil
+}
+
+// nextReady returns the index of the ready item which was enqueued first,
+// or -1 and how long until the next item will be ready (zero if there are none).
+func nextReady(items []*WebhookQueueItem, now time.Time) (int, time.Duration) {
+	next, wait := -1, time.Duration(0)
+	for i, it := range items {
+		if it.RetryAt == nil || !it.RetryAt.After(now) {
+			if next < 0 || it.EnqueuedAt.Before(items[next].EnqueuedAt) {
+				next = i
+			}
+			continue
+		}
+		if d := it.RetryAt.Sub(now); wait == 0 || d < wait {
+			wait = d
+		}
+	}
+	return next, wait
+}
+
+// waitForItem blocks until notify fires, wait elapses (if non-zero) or ctx is done.
+func waitForItem(ctx context.Context, notify <-chan struct{}, wait time.Duration) error {
+	var timer <-chan time.Time
+	if wait > 0 {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		timer = t.C
+	}
+	select {
+	case <-notify:
+	case <-timer:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+// MemoryWebhookQueue is an in-memory WebhookQueue. Items are lost when the
+// process exits, so it is best suited to tests and development.
+type MemoryWebhookQueue struct {
+	mu      sync.Mutex
+	pending []*WebhookQueueItem
+	dead    []*WebhookQueueItem
+	notify  chan struct{}
+}
+
+var _ WebhookQueue = &MemoryWebhookQueue{}
+
+// NewMemoryWebhookQueue creates a new in-memory queue.
+func NewMemoryWebhookQueue() *MemoryWebhookQueue {
+	return &MemoryWebhookQueue{notify: make(chan struct{}, 1)}
+}
+
+func (q *MemoryWebhookQueue) push(item *WebhookQueueItem) {
+	q.mu.Lock()
+	q.pending = append(q.pending, item)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue implements WebhookQueue.
+func (q *MemoryWebhookQueue) Enqueue(_ context.Context, item *WebhookQueueItem) error {
+	q.push(item)
+	return nil
+}
+
+// Dequeue implements WebhookQueue.
+func (q *MemoryWebhookQueue) Dequeue(ctx context.Context) (*WebhookQueueItem, error) {
+	for {
+		q.mu.Lock()
+		i, wait := nextReady(q.pending, time.Now())
+		if i >= 0 {
+			item := q.pending[i]
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			q.mu.Unlock()
+			return item, nil
+		}
+		q.mu.Unlock()
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := waitForItem(ctx, q.notify, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Ack implements WebhookQueue.
+func (q *MemoryWebhookQueue) Ack(context.Context, *WebhookQueueItem) error {
+	return nil
+}
+
+// Nack implements WebhookQueue.
+func (q *MemoryWebhookQueue) Nack(_ context.Context, item *WebhookQueueItem) error {
+	q.push(item)
+	return nil
+}
+
+// DeadLetter implements WebhookQueue.
+func (q *MemoryWebhookQueue) DeadLetter(_ context.Context, item *WebhookQueueItem) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dead = append(q.dead, item)
+	return nil
+}
+
+// Len returns the number of items waiting in the queue.
+func (q *MemoryWebhookQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// DeadLetters returns the items which have been dead lettered.
+func (q *MemoryWebhookQueue) DeadLetters() []*WebhookQueueItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*WebhookQueueItem(nil), q.dead...)
+}
+
+// fileQueuePollInterval is how often a FileWebhookQueue checks for items
+// enqueued by other processes.
+const fileQueuePollInterval = time.Second
+
+// fileQueueTempMaxAge is how old a temporary file must be before it is
+// assumed to be left over from a crashed write and removed.
+const fileQueueTempMaxAge = time.Hour
+
+// FileWebhookQueue is a WebhookQueue storing each item as a JSON file in
+// the pending, inflight and dead sub-directories of a directory. Items left
+// in flight by a crash are returned to the queue when it is opened.
+//
+// Any number of processes may Enqueue into the same directory, but claims
+// are only serialised within a process and opening the queue recovers
+// every in-flight item, so only one process may Dequeue from it at a time.
+// Pending files which can't be read or decoded are moved to the dead
+// sub-directory as they are.
+type FileWebhookQueue struct {
+	dir    string
+	mu     sync.Mutex
+	notify chan struct{}
+	// pending caches the decoded pending items by file name, so that each
+	// claim only reads files which are new or have changed.
+	pending map[string]*fileQueueEntry
+}
+
+// fileQueueEntry is a decoded pending item and the file it was read from.
+type fileQueueEntry struct {
+	modTime time.Time
+	size    int64
+	item    *WebhookQueueItem
+}
+
+var _ WebhookQueue = &FileWebhookQueue{}
+
+// Sub-directories of a FileWebhookQueue
+const (
+	fileQueuePending  = "pending"
+	fileQueueInflight = "inflight"
+	fileQueueDead     = "dead"
+)
+
+// fileQueueTempPrefix prefixes the temporary files items are written to
+// before being renamed into place.
+const fileQueueTempPrefix = ".item-"
+
+// OpenFileWebhookQueue opens, creating if needed, a file backed queue in dir.
+func OpenFileWebhookQueue(dir string) (*FileWebhookQueue, error) {
+	for _, sub := range []string{fileQueuePending, fileQueueInflight, fileQueueDead} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	q := &FileWebhookQueue{
+		dir:     dir,
+		notify:  make(chan struct{}, 1),
+		pending: make(map[string]*fileQueueEntry),
+	}
+
+	// Recover items claimed by a previous process which never finished them
+	inflight, err := q.list(fileQueueInflight)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range inflight {
+		if err := os.Rename(q.path(fileQueueInflight, fi.Name()), q.path(fileQueuePending, fi.Name())); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := q.removeStaleTemp(time.Now()); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// removeStaleTemp removes temporary files left behind by writes which were
+// interrupted before they could clean up. Recent files are kept, as they
+// may belong to a write still in progress in another process.
+func (q *FileWebhookQueue) removeStaleTemp(now time.Time) error {
+	infos, err := ioutil.ReadDir(q.dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range infos {
+		if fi.IsDir() || !strings.HasPrefix(fi.Name(), fileQueueTempPrefix) || now.Sub(fi.ModTime()) < fileQueueTempMaxAge {
+			continue
+		}
+		if err := os.Remove(filepath.Join(q.dir, fi.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *FileWebhookQueue) path(sub, name string) string {
+	return filepath.Join(q.dir, sub, name)
+}
+
+func (q *FileWebhookQueue) list(sub string) ([]os.FileInfo, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(q.dir, sub))
+	if err != nil {
+		return nil, err
+	}
+	var items []os.FileInfo
+	for _, fi := range infos {
+		if !fi.IsDir() && strings.HasSuffix(fi.Name(), ".json") {
+			items = append(items, fi)
+		}
+	}
+	return items, nil
+}
+
+// write atomically writes the item into the given sub-directory. The
+// temporary file is removed if any step fails.
+func (q *FileWebhookQueue) write(sub string, item *WebhookQueueItem) (err error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(q.dir, fileQueueTempPrefix)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), q.path(sub, item.ID+".json"))
+}
+
+func (q *FileWebhookQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue implements WebhookQueue.
+func (q *FileWebhookQueue) Enqueue(_ context.Context, item *WebhookQueueItem) error {
+	if err := q.write(fileQueuePending, item); err != nil {
+		return err
+	}
+	q.signal()
+	return nil
+}
+
+// Dequeue implements WebhookQueue.
+func (q *FileWebhookQueue) Dequeue(ctx context.Context) (*WebhookQueueItem, error) {
+	for {
+		item, wait, err := q.claim()
+		if err != nil || item != nil {
+			return item, err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if wait == 0 || wait > fileQueuePollInterval {
+			wait = fileQueuePollInterval
+		}
+		if err := waitForItem(ctx, q.notify, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// claim moves the next ready item into the inflight directory.
+func (q *FileWebhookQueue) claim() (*WebhookQueueItem, time.Duration, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if err := q.refresh(); err != nil {
+			return nil, 0, err
+		}
+
+		names := make([]string, 0, len(q.pending))
+		for name := range q.pending {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		items := make([]*WebhookQueueItem, len(names))
+		for i, name := range names {
+			items[i] = q.pending[name].item
+		}
+
+		i, wait := nextReady(items, time.Now())
+		if i < 0 {
+			return nil, wait, nil
+		}
+		delete(q.pending, names[i])
+		err := os.Rename(q.path(fileQueuePending, names[i]), q.path(fileQueueInflight, names[i]))
+		if os.IsNotExist(err) {
+			// Removed since the directory was listed, try the next item
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		return items[i], 0, nil
+	}
+}
+
+// refresh brings the cache of pending items up to date with the pending
+// directory, reading only files which are new or have changed.
+func (q *FileWebhookQueue) refresh() error {
+	infos, err := q.list(fileQueuePending)
+	if err != nil {
+		return err
+	}
+
+	present := make(map[string]bool, len(infos))
+	for _, fi := range infos {
+		name := fi.Name()
+		present[name] = true
+		if e, ok := q.pending[name]; ok && e.modTime.Equal(fi.ModTime()) && e.size == fi.Size() {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(q.path(fileQueuePending, name))
+		if os.IsNotExist(err) {
+			delete(q.pending, name)
+			continue
+		}
+		var item WebhookQueueItem
+		if err == nil {
+			err = json.Unmarshal(data, &item)
+		}
+		if err != nil {
+			// Set aside files which can't be processed so they don't
+			// block the rest of the queue
+			delete(q.pending, name)
+			if err := os.Rename(q.path(fileQueuePending, name), q.path(fileQueueDead, name)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		q.pending[name] = &fileQueueEntry{modTime: fi.ModTime(), size: fi.Size(), item: &item}
+	}
+
+	for name := range q.pending {
+		if !present[name] {
+			delete(q.pending, name)
+		}
+	}
+	return nil
+}
+
+// Ack implements WebhookQueue.
+func (q *FileWebhookQueue) Ack(_ context.Context, item *WebhookQueueItem) error {
+	return os.Remove(q.path(fileQueueInflight, item.ID+".json"))
+}
+
+// Nack implements WebhookQueue.
+func (q *FileWebhookQueue) Nack(_ context.Context, item *WebhookQueueItem) error {
+	if err := q.write(fileQueuePending, item); err != nil {
+		return err
+	}
+	q.signal()
+	return os.Remove(q.path(fileQueueInflight, item.ID+".json"))
+}
+
+// DeadLetter implements WebhookQueue.
+func (q *FileWebhookQueue) DeadLetter(_ context.Context, item *WebhookQueueItem) error {
+	if err := q.write(fileQueueDead, item); err != nil {
+		return err
+	}
+	return os.Remove(q.path(fileQueueInflight, item.ID+".json"))
+}
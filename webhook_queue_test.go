@@ -0,0 +1,334 @@
+package onfido
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryWebhookQueue(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryWebhookQueue()
+
+	item, err := NewWebhookQueueItem([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, q.Enqueue(ctx, item))
+
+	got, err := q.Dequeue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, item.ID, got.ID)
+
+	// Items are not returned before their retry time
+	retryAt := time.Now().Add(time.Hour)
+	got.RetryAt = &retryAt
+	assert.NoError(t, q.Nack(ctx, got))
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = q.Dequeue(cancelled)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, q.Len())
+}
+
+func TestFileWebhookQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onfido-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	q, err := OpenFileWebhookQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := NewWebhookQueueItem([]byte(`{"n": 1}`))
+	second, _ := NewWebhookQueueItem([]byte(`{"n": 2}`))
+	assert.NoError(t, q.Enqueue(ctx, first))
+	assert.NoError(t, q.Enqueue(ctx, second))
+
+	got, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, q.Ack(ctx, got))
+
+	got, err = q.Dequeue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, `{"n": 2}`, string(got.Body))
+	got.Attempts = 1
+	assert.NoError(t, q.DeadLetter(ctx, got))
+
+	dead, _ := filepath.Glob(filepath.Join(dir, "dead", "*.json"))
+	assert.Len(t, dead, 1)
+
+	// An item left in flight is recovered when the queue is reopened
+	third, _ := NewWebhookQueueItem([]byte(`{"n": 3}`))
+	assert.NoError(t, q.Enqueue(ctx, third))
+	_, err = q.Dequeue(ctx)
+	assert.NoError(t, err)
+
+	q, err = OpenFileWebhookQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = q.Dequeue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, third.ID, got.ID)
+}
+
+func TestFileWebhookQueue_CorruptItem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onfido-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	q, err := OpenFileWebhookQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A corrupt file is set aside rather than blocking the items behind it
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "pending", "0000.json"), []byte("{not json"), 0600))
+	item, _ := NewWebhookQueueItem([]byte(`{"n": 1}`))
+	assert.NoError(t, q.Enqueue(ctx, item))
+
+	got, err := q.Dequeue(ctx)
+	if assert.NoError(t, err) {
+		assert.Equal(t, item.ID, got.ID)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "dead", "0000.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, "{not json", string(data))
+	pending, _ := filepath.Glob(filepath.Join(dir, "pending", "*.json"))
+	assert.Empty(t, pending)
+}
+
+func TestFileWebhookQueue_RemovesStaleTempFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onfido-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stale := filepath.Join(dir, ".item-stale")
+	recent := filepath.Join(dir, ".item-recent")
+	assert.NoError(t, ioutil.WriteFile(stale, []byte("{"), 0600))
+	assert.NoError(t, ioutil.WriteFile(recent, []byte("{"), 0600))
+	old := time.Now().Add(-2 * fileQueueTempMaxAge)
+	assert.NoError(t, os.Chtimes(stale, old, old))
+
+	_, err = OpenFileWebhookQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(stale)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(recent)
+	assert.NoError(t, err)
+}
+
+func TestFileWebhookQueue_RetryAt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onfido-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	q, err := OpenFileWebhookQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item, _ := NewWebhookQueueItem([]byte(`{}`))
+	assert.NoError(t, q.Enqueue(ctx, item))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "pending", item.ID+".json"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "retry_at")
+
+	// A nacked item isn't claimed again before its retry time
+	got, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retryAt := time.Now().Add(time.Hour)
+	got.RetryAt = &retryAt
+	assert.NoError(t, q.Nack(ctx, got))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = q.Dequeue(cancelled)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestWebhookHandler_Async(t *testing.T) {
+	q := NewMemoryWebhookQueue()
+	h := NewWebhookHandler(NewWebhook("abc123"), WebhookHandlerOptions{
+		ErrorLog:     log.New(ioutil.Discard, "", 0),
+		Queue:        q,
+		MaxAttempts:  3,
+		RetryBackoff: time.Millisecond,
+	})
+
+	var mu sync.Mutex
+	calls := 0
+	done := make(chan struct{})
+	h.OnCheckCompleted(func(ctx context.Context, wr *WebhookRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls < 2 {
+			return errors.New("downstream unavailable")
+		}
+		assert.Equal(t, "check-1", wr.Payload.Object.ID)
+		close(done)
+		return nil
+	})
+
+	rec := serveWebhook(t, h, testWebhookBody, signWebhookBody(t, "abc123", []byte(testWebhookBody)))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	assert.NoError(t, h.Start(context.Background(), 2))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for queued event")
+	}
+
+	assert.NoError(t, h.Shutdown(context.Background()))
+	rec = serveWebhook(t, h, testWebhookBody, signWebhookBody(t, "abc123", []byte(testWebhookBody)))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Empty(t, q.DeadLetters())
+}
+
+func TestWebhookHandler_AsyncDeadLetter(t *testing.T) {
+	q := NewMemoryWebhookQueue()
+	h := NewWebhookHandler(NewWebhook("abc123"), WebhookHandlerOptions{
+		ErrorLog:     log.New(ioutil.Discard, "", 0),
+		Queue:        q,
+		MaxAttempts:  2,
+		RetryBackoff: time.Millisecond,
+	})
+	h.OnCheckCompleted(func(ctx context.Context, wr *WebhookRequest) error {
+		return errors.New("always fails")
+	})
+
+	serveWebhook(t, h, testWebhookBody, signWebhookBody(t, "abc123", []byte(testWebhookBody)))
+	assert.NoError(t, h.Start(context.Background(), 1))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(q.DeadLetters()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, h.Shutdown(context.Background()))
+
+	if assert.Len(t, q.DeadLetters(), 1) {
+		assert.Equal(t, 2, q.DeadLetters()[0].Attempts)
+		assert.Equal(t, "always fails", q.DeadLetters()[0].LastError)
+	}
+}
+
+func TestWebhookHandler_ShutdownDrains(t *testing.T) {
+	q := NewMemoryWebhookQueue()
+	h := NewWebhookHandler(NewWebhook("abc123"), WebhookHandlerOptions{Queue: q})
+
+	var mu sync.Mutex
+	calls := 0
+	h.OnCheckCompleted(func(ctx context.Context, wr *WebhookRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return nil
+	})
+	for i := 0; i < 3; i++ {
+		serveWebhook(t, h, testWebhookBody, signWebhookBody(t, "abc123", []byte(testWebhookBody)))
+	}
+
+	// Events already queued are processed before Shutdown returns
+	assert.NoError(t, h.Start(context.Background(), 1))
+	assert.NoError(t, h.Shutdown(context.Background()))
+	assert.Equal(t, 3, calls)
+}
+
+// blockingWebhookQueue is a MemoryWebhookQueue whose Enqueue waits until
+// release is closed.
+type blockingWebhookQueue struct {
+	*MemoryWebhookQueue
+	enqueuing chan struct{}
+	release   chan struct{}
+}
+
+func (q *blockingWebhookQueue) Enqueue(ctx context.Context, item *WebhookQueueItem) error {
+	close(q.enqueuing)
+	<-q.release
+	return q.MemoryWebhookQueue.Enqueue(ctx, item)
+}
+
+func TestWebhookHandler_ShutdownWaitsForEnqueue(t *testing.T) {
+	q := &blockingWebhookQueue{NewMemoryWebhookQueue(), make(chan struct{}), make(chan struct{})}
+	h := NewWebhookHandler(NewWebhook("abc123"), WebhookHandlerOptions{Queue: q})
+
+	var mu sync.Mutex
+	calls := 0
+	h.OnCheckCompleted(func(ctx context.Context, wr *WebhookRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return nil
+	})
+	assert.NoError(t, h.Start(context.Background(), 1))
+
+	served := make(chan int)
+	go func() {
+		served <- serveWebhook(t, h, testWebhookBody, signWebhookBody(t, "abc123", []byte(testWebhookBody))).Code
+	}()
+	<-q.enqueuing
+
+	shutdown := make(chan error)
+	go func() { shutdown <- h.Shutdown(context.Background()) }()
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned while an event was being queued")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The event accepted during Shutdown is processed before it returns
+	close(q.release)
+	assert.Equal(t, http.StatusAccepted, <-served)
+	assert.NoError(t, <-shutdown)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, calls)
+}
+
+func TestWebhookHandler_StartWithoutQueue(t *testing.T) {
+	h := newTestWebhookHandler()
+	assert.Equal(t, ErrWebhookQueueNotConfigured, h.Start(context.Background(), 1))
+}
+
+func TestWebhookHandler_RetryBackoff(t *testing.T) {
+	h := NewWebhookHandler(NewWebhook("abc123"), WebhookHandlerOptions{
+		RetryBackoff:    time.Second,
+		MaxRetryBackoff: 5 * time.Second,
+	})
+	assert.Equal(t, time.Second, h.retryBackoff(1))
+	assert.Equal(t, 2*time.Second, h.retryBackoff(2))
+	assert.Equal(t, 4*time.Second, h.retryBackoff(3))
+	assert.Equal(t, 5*time.Second, h.retryBackoff(4))
+}
@@ -0,0 +1,279 @@
+package onfido
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// checkStatusEvents maps check statuses to the event announcing them.
+// Paused checks have no event.
+var checkStatusEvents = map[CheckStatus]WebhookEvent{
+	CheckStatusInProgress:        WebhookEventCheckStarted,
+	CheckStatusAwaitingApplicant: WebhookEventCheckFormOpened,
+	CheckStatusReopened:          WebhookEventCheckReopened,
+	CheckStatusWithdrawn:         WebhookEventCheckWithdrawn,
+	CheckStatusComplete:          WebhookEventCheckCompleted,
+}
+
+// WebhookReconciler polls checks for status changes whose webhook events
+// may have been missed, e.g. because the webhook endpoint was down for
+// longer than Onfido retries deliveries. Changes are emitted as synthetic
+// WebhookRequests to the deliver function, usually a WebhookHandler's
+// Deliver method, so downstream handlers see the same events whether they
+// were pushed or pulled. It is safe for concurrent use.
+type WebhookReconciler struct {
+	// ErrorLog logs errors from Run, the standard logger if nil.
+	ErrorLog *log.Logger
+
+	client  OnfidoClient
+	deliver WebhookHandlerFunc
+
+	mu         sync.Mutex
+	checks     map[string]CheckStatus
+	applicants map[string]map[string]CheckStatus
+}
+
+// NewWebhookReconciler creates a new reconciler polling with client and
+// emitting events to deliver.
+func NewWebhookReconciler(client OnfidoClient, deliver WebhookHandlerFunc) *WebhookReconciler {
+	return &WebhookReconciler{
+		client:     client,
+		deliver:    deliver,
+		checks:     make(map[string]CheckStatus),
+		applicants: make(map[string]map[string]CheckStatus),
+	}
+}
+
+// TrackCheck polls the check until it is complete or withdrawn. status is
+// its last known status, an event is emitted once it changes.
+func (r *WebhookReconciler) TrackCheck(id string, status CheckStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[id] = status
+}
+
+// TrackApplicant polls the applicant's checks until UntrackApplicant is
+// called. Checks seen for the first time emit an event for their current
+// status, so only applicants with checks pending should be tracked.
+func (r *WebhookReconciler) TrackApplicant(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.applicants[id]; !ok {
+		r.applicants[id] = make(map[string]CheckStatus)
+	}
+}
+
+// UntrackCheck stops polling the check.
+func (r *WebhookReconciler) UntrackCheck(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.checks, id)
+}
+
+// UntrackApplicant stops polling the applicant's checks.
+func (r *WebhookReconciler) UntrackApplicant(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.applicants, id)
+}
+
+// Observe records the status implied by a received webhook event, so the
+// change it announces isn't emitted again.
+func (r *WebhookReconciler) Observe(wr *WebhookRequest) {
+	status, ok := checkEventStatuses[wr.Payload.Action]
+	if !ok {
+		return
+	}
+	id := wr.Payload.Object.ID
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.checks[id]; ok {
+		r.setCheck(id, status)
+	}
+	for _, checks := range r.applicants {
+		if _, ok := checks[id]; ok {
+			checks[id] = status
+		}
+	}
+}
+
+// setCheck records a tracked check's status, untracking it once it has finished.
+func (r *WebhookReconciler) setCheck(id string, status CheckStatus) {
+	if status.IsTerminal() {
+		delete(r.checks, id)
+	} else {
+		r.checks[id] = status
+	}
+}
+
+// Reconcile polls the tracked checks and applicants once, emitting an event
+// for each check whose status has changed. It returns the number of events
+// emitted and the first error. A change whose event fails to be delivered
+// is emitted again by the next call.
+func (r *WebhookReconciler) Reconcile(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	checkIDs := make([]string, 0, len(r.checks))
+	for id := range r.checks {
+		checkIDs = append(checkIDs, id)
+	}
+	applicantIDs := make([]string, 0, len(r.applicants))
+	for id := range r.applicants {
+		applicantIDs = append(applicantIDs, id)
+	}
+	r.mu.Unlock()
+
+	var emitted int
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for _, applicantID := range applicantIDs {
+		it := r.client.ListChecks(applicantID)
+		for it.Next(ctx) {
+			chk := it.Check()
+			ok, err := r.reconcileApplicantCheck(ctx, applicantID, chk.ID, chk.Status, chk.Href)
+			if ok {
+				emitted++
+			}
+			if err != nil {
+				fail(err)
+			}
+		}
+		if err := it.Err(); err != nil {
+			fail(err)
+		}
+	}
+
+	for _, id := range checkIDs {
+		chk, err := r.client.GetCheck(ctx, id)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		ok, err := r.reconcileCheck(ctx, id, chk.Status, chk.Href)
+		if ok {
+			emitted++
+		}
+		if err != nil {
+			fail(err)
+		}
+	}
+
+	return emitted, firstErr
+}
+
+// reconcileCheck emits an event if a tracked check's status has changed.
+func (r *WebhookReconciler) reconcileCheck(ctx context.Context, id string, status CheckStatus, href string) (bool, error) {
+	r.mu.Lock()
+	last, tracked := r.checks[id]
+	if !tracked || last == status {
+		r.mu.Unlock()
+		return false, nil
+	}
+	r.setCheck(id, status)
+	r.mu.Unlock()
+
+	emitted, err := r.emit(ctx, id, status, href)
+	if err != nil {
+		r.mu.Lock()
+		r.checks[id] = last
+		r.mu.Unlock()
+	}
+	return emitted, err
+}
+
+// reconcileApplicantCheck emits an event if one of a tracked applicant's
+// checks is new or its status has changed.
+func (r *WebhookReconciler) reconcileApplicantCheck(ctx context.Context, applicantID, id string, status CheckStatus, href string) (bool, error) {
+	r.mu.Lock()
+	checks, tracked := r.applicants[applicantID]
+	if !tracked {
+		r.mu.Unlock()
+		return false, nil
+	}
+	last, seen := checks[id]
+	if seen && last == status {
+		r.mu.Unlock()
+		return false, nil
+	}
+	checks[id] = status
+	r.mu.Unlock()
+
+	emitted, err := r.emit(ctx, id, status, href)
+	if err != nil {
+		r.mu.Lock()
+		if seen {
+			checks[id] = last
+		} else {
+			delete(checks, id)
+		}
+		r.mu.Unlock()
+	}
+	return emitted, err
+}
+
+// emit delivers a synthetic event announcing the check's status, if it has
+// one. Checks don't record when their status changed, so the event has no
+// completion time; a WebhookHandler recognises the pushed event for the same
+// change as one completed before the change was delivered, and drops it.
+func (r *WebhookReconciler) emit(ctx context.Context, id string, status CheckStatus, href string) (bool, error) {
+	action, ok := checkStatusEvents[status]
+	if !ok {
+		return false, nil
+	}
+
+	wr := &WebhookRequest{
+		Payload: WebhookPayload{
+			ResourceType: WebhookResourceCheck,
+			Action:       action,
+			Object: WebhookObject{
+				ID:     id,
+				Status: string(status),
+				Href:   href,
+			},
+		},
+		TokenIndex: -1,
+	}
+	raw, err := json.Marshal(wr)
+	if err != nil {
+		return false, err
+	}
+	wr.Raw = raw
+
+	if err := r.deliver(ctx, wr); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Run calls Reconcile every interval until ctx is done, logging any errors.
+// It returns ctx.Err().
+func (r *WebhookReconciler) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Reconcile(ctx); err != nil && ctx.Err() == nil {
+			r.logf("onfido: webhook reconcile failed: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (r *WebhookReconciler) logf(format string, args ...interface{}) {
+	if r.ErrorLog != nil {
+		r.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
@@ -0,0 +1,297 @@
+package onfido
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookReconciler_TrackCheck(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	var events []*WebhookRequest
+	r := NewWebhookReconciler(client, func(ctx context.Context, wr *WebhookRequest) error {
+		events = append(events, wr)
+		return nil
+	})
+
+	srv.setCheck("check-1", "applicant-1", CheckStatusInProgress)
+	r.TrackCheck("check-1", CheckStatusInProgress)
+
+	n, err := r.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	srv.setCheck("check-1", "applicant-1", CheckStatusComplete)
+	n, err = r.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, WebhookResourceCheck, events[0].Payload.ResourceType)
+		assert.Equal(t, WebhookEventCheckCompleted, events[0].Payload.Action)
+		assert.Equal(t, "check-1", events[0].Payload.Object.ID)
+		assert.Equal(t, "complete", events[0].Payload.Object.Status)
+		assert.Equal(t, "/v3.1/checks/check-1", events[0].Payload.Object.Href)
+		assert.Equal(t, -1, events[0].TokenIndex)
+
+		var decoded WebhookRequest
+		assert.NoError(t, json.Unmarshal(events[0].Raw, &decoded))
+		assert.Equal(t, events[0].Payload.Action, decoded.Payload.Action)
+	}
+
+	// Completed checks are no longer polled
+	n, err = r.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestWebhookReconciler_TrackApplicant(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	var events []WebhookEvent
+	r := NewWebhookReconciler(client, func(ctx context.Context, wr *WebhookRequest) error {
+		events = append(events, wr.Payload.Action)
+		return nil
+	})
+
+	srv.setCheck("check-1", "applicant-1", CheckStatusPaused)
+	srv.setCheck("check-2", "applicant-2", CheckStatusComplete)
+	r.TrackApplicant("applicant-1")
+
+	// Paused checks have no event
+	n, err := r.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	srv.setCheck("check-1", "applicant-1", CheckStatusWithdrawn)
+	n, err = r.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = r.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, []WebhookEvent{WebhookEventCheckWithdrawn}, events)
+}
+
+func TestWebhookReconciler_Observe(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	r := NewWebhookReconciler(client, func(ctx context.Context, wr *WebhookRequest) error {
+		t.Fatalf("unexpected event %s", wr.Payload.Action)
+		return nil
+	})
+
+	srv.setCheck("check-1", "applicant-1", CheckStatusComplete)
+	r.TrackCheck("check-1", CheckStatusInProgress)
+	r.Observe(webhookEvent(WebhookResourceCheck, WebhookEventCheckCompleted, "check-1"))
+
+	n, err := r.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestWebhookReconciler_DeliveryFailureRetried(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	fail := true
+	r := NewWebhookReconciler(client, func(ctx context.Context, wr *WebhookRequest) error {
+		if fail {
+			return errors.New("handler unavailable")
+		}
+		return nil
+	})
+
+	srv.setCheck("check-1", "applicant-1", CheckStatusComplete)
+	r.TrackCheck("check-1", CheckStatusInProgress)
+
+	n, err := r.Reconcile(context.Background())
+	assert.EqualError(t, err, "handler unavailable")
+	assert.Equal(t, 0, n)
+
+	fail = false
+	n, err = r.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestWebhookReconciler_ThroughHandler(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	h := NewWebhookHandler(NewWebhook("abc123"), WebhookHandlerOptions{DedupStore: NewMemoryDedupStore(10)})
+	var completed []string
+	h.OnCheckCompleted(func(ctx context.Context, wr *WebhookRequest) error {
+		completed = append(completed, wr.Payload.Object.ID)
+		return nil
+	})
+
+	r := NewWebhookReconciler(client, h.Deliver)
+	srv.setCheck("check-1", "applicant-1", CheckStatusComplete)
+	r.TrackCheck("check-1", CheckStatusInProgress)
+
+	n, err := r.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"check-1"}, completed)
+
+	// The late push of the reconciled change isn't dispatched again, nor
+	// are its redeliveries
+	body := `{"payload": {"resource_type": "check", "action": "check.completed", "object": {"id": "check-1", "status": "complete", "completed_at_iso8601": "2020-05-12T10:37:04Z"}}}`
+	for i := 0; i < 2; i++ {
+		rec := serveWebhook(t, h, body, signWebhookBody(t, "abc123", []byte(body)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Equal(t, []string{"check-1"}, completed)
+
+	// Once consumed, the check completing again after being reopened is dispatched
+	body = `{"payload": {"resource_type": "check", "action": "check.completed", "object": {"id": "check-1", "status": "complete", "completed_at_iso8601": "2020-05-13T09:00:00Z"}}}`
+	rec := serveWebhook(t, h, body, signWebhookBody(t, "abc123", []byte(body)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"check-1", "check-1"}, completed)
+}
+
+func TestWebhookReconciler_ThroughHandler_Reopened(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	h := NewWebhookHandler(NewWebhook("abc123"), WebhookHandlerOptions{DedupStore: NewMemoryDedupStore(10)})
+	var events []WebhookEvent
+	h.Fallback(func(ctx context.Context, wr *WebhookRequest) error {
+		events = append(events, wr.Payload.Action)
+		return nil
+	})
+
+	r := NewWebhookReconciler(client, h.Deliver)
+	srv.setCheck("check-1", "applicant-1", CheckStatusComplete)
+	r.TrackApplicant("applicant-1")
+	_, err := r.Reconcile(context.Background())
+	assert.NoError(t, err)
+
+	// The check completing again after a missed reopen is pushed with a
+	// later completion time
+	body := `{"payload": {"resource_type": "check", "action": "check.completed", "object": {"id": "check-1", "status": "complete", "completed_at_iso8601": "` +
+		time.Now().Add(time.Minute).UTC().Format(time.RFC3339) + `"}}}`
+	assert.Equal(t, http.StatusOK, serveWebhook(t, h, body, signWebhookBody(t, "abc123", []byte(body))).Code)
+	assert.Equal(t, []WebhookEvent{WebhookEventCheckCompleted, WebhookEventCheckCompleted}, events)
+
+	// A reopen found by the reconciler supersedes the completion, so the
+	// next completion it finds is dispatched
+	srv.setCheck("check-1", "applicant-1", CheckStatusReopened)
+	_, err = r.Reconcile(context.Background())
+	assert.NoError(t, err)
+	srv.setCheck("check-1", "applicant-1", CheckStatusComplete)
+	_, err = r.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []WebhookEvent{
+		WebhookEventCheckCompleted,
+		WebhookEventCheckCompleted,
+		WebhookEventCheckReopened,
+		WebhookEventCheckCompleted,
+	}, events)
+}
+
+func TestWebhookReconciler_ThroughHandler_PushedFirst(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	h := NewWebhookHandler(NewWebhook("abc123"), WebhookHandlerOptions{DedupStore: NewMemoryDedupStore(10)})
+	completed := 0
+	h.OnCheckCompleted(func(ctx context.Context, wr *WebhookRequest) error {
+		completed++
+		return nil
+	})
+
+	body := `{"payload": {"resource_type": "check", "action": "check.completed", "object": {"id": "check-1", "status": "complete", "completed_at_iso8601": "2020-05-12T10:37:04Z"}}}`
+	assert.Equal(t, http.StatusOK, serveWebhook(t, h, body, signWebhookBody(t, "abc123", []byte(body))).Code)
+
+	// The reconciler finding the pushed change without Observe being
+	// called isn't dispatched again
+	r := NewWebhookReconciler(client, h.Deliver)
+	srv.setCheck("check-1", "applicant-1", CheckStatusComplete)
+	r.TrackCheck("check-1", CheckStatusInProgress)
+	n, err := r.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 1, completed)
+}
+
+func TestWebhookReconciler_ThroughHandler_Window(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	h := NewWebhookHandler(NewWebhook("abc123"), WebhookHandlerOptions{DedupStore: NewMemoryDedupStore(10)})
+	completed := 0
+	fail := true
+	h.OnCheckCompleted(func(ctx context.Context, wr *WebhookRequest) error {
+		completed++
+		if fail {
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	})
+
+	// A reconciled change which fails to be dispatched is emitted again
+	r := NewWebhookReconciler(client, h.Deliver)
+	srv.setCheck("check-1", "applicant-1", CheckStatusComplete)
+	r.TrackCheck("check-1", CheckStatusInProgress)
+	_, err := r.Reconcile(context.Background())
+	assert.Error(t, err)
+	fail = false
+	n, err := r.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 2, completed)
+
+	// A push arriving after the reconcile window is dispatched
+	h.now = func() time.Time { return time.Now().Add(DefaultWebhookReconcileWindow + time.Minute) }
+	body := `{"payload": {"resource_type": "check", "action": "check.completed", "object": {"id": "check-1", "status": "complete", "completed_at_iso8601": "2020-05-12T10:37:04Z"}}}`
+	assert.Equal(t, http.StatusOK, serveWebhook(t, h, body, signWebhookBody(t, "abc123", []byte(body))).Code)
+	assert.Equal(t, 3, completed)
+}
+
+func TestWebhookReconciler_GetCheckError(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	r := NewWebhookReconciler(client, func(ctx context.Context, wr *WebhookRequest) error { return nil })
+	r.TrackCheck("missing", CheckStatusInProgress)
+
+	_, err := r.Reconcile(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,223 @@
+package onfido
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// webhookObjectCacheTTL is how long resolved webhook objects are cached for.
+const webhookObjectCacheTTL = time.Minute
+
+// ErrMissingWebhookObject is returned when a webhook request has no object to resolve
+var ErrMissingWebhookObject = errors.New("webhook request has no object id or href")
+
+// ResolveWebhookObject fetches the resource a webhook event refers to. It
+// returns a *Check with its reports expanded for check events, a *Report
+// for report events, a *Document for document events, and the resource's JSON as a json.RawMessage for
+// other resource types.
+//
+// Results are cached by resource and status for a short time, so a burst
+// of events for the same resource only fetches it once. Each caller gets
+// its own copy of the resource, and may stop waiting for a shared fetch
+// by cancelling ctx without failing the others.
+func (c *client) ResolveWebhookObject(ctx context.Context, wr *WebhookRequest) (interface{}, error) {
+	obj := wr.Payload.Object
+	resourceType := wr.Payload.ResourceType
+	if resourceType == "" {
+		resourceType = wr.Payload.Action.ResourceType()
+	}
+
+	var key string
+	var fetch func(ctx context.Context) (interface{}, error)
+	switch resourceType {
+	case WebhookResourceCheck:
+		if obj.ID == "" {
+			return nil, ErrMissingWebhookObject
+		}
+		key = string(resourceType) + "/" + obj.ID + "/" + obj.Status
+		fetch = func(ctx context.Context) (interface{}, error) { return c.GetCheckExpanded(ctx, obj.ID) }
+	case WebhookResourceReport:
+		if obj.ID == "" {
+			return nil, ErrMissingWebhookObject
+		}
+		key = string(resourceType) + "/" + obj.ID + "/" + obj.Status
+		fetch = func(ctx context.Context) (interface{}, error) { return c.GetReport(ctx, obj.ID) }
+	case WebhookResourceDocument:
+		if obj.ID == "" {
+			return nil, ErrMissingWebhookObject
+		}
+		key = string(resourceType) + "/" + obj.ID
+		fetch = func(ctx context.Context) (interface{}, error) { return c.GetDocument(ctx, obj.ID) }
+	default:
+		if obj.Href == "" {
+			return nil, ErrMissingWebhookObject
+		}
+		key = obj.Href + "/" + obj.Status
+		fetch = func(ctx context.Context) (interface{}, error) {
+			var raw json.RawMessage
+			if err := c.GetResource(ctx, obj.Href, &raw); err != nil {
+				return nil, err
+			}
+			return raw, nil
+		}
+	}
+
+	return c.objectCache.get(ctx, key, fetch)
+}
+
+// objectCache caches fetched resources, collapsing concurrent fetches of the same key.
+type objectCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]*objectCacheEntry
+}
+
+type objectCacheEntry struct {
+	done    chan struct{}
+	value   interface{}
+	err     error
+	expires time.Time
+
+	// waiters is how many callers are waiting for the fetch, which is
+	// cancelled if they all stop waiting.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+func newObjectCache(ttl time.Duration) *objectCache {
+	return &objectCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*objectCacheEntry),
+	}
+}
+
+// get returns a copy of the value cached for key, fetching it if needed.
+// The fetch runs on a context of its own so that it is shared by every
+// caller waiting for the key, each of which waits until its ctx is done.
+func (oc *objectCache) get(ctx context.Context, key string, fetch func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	oc.mu.Lock()
+	now := oc.now()
+	for k, e := range oc.entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(oc.entries, k)
+		}
+	}
+	e, ok := oc.entries[key]
+	if !ok {
+		fetchCtx, cancel := context.WithCancel(context.Background())
+		e = &objectCacheEntry{done: make(chan struct{}), cancel: cancel}
+		oc.entries[key] = e
+		go oc.fetch(fetchCtx, key, e, fetch)
+	}
+	e.waiters++
+	oc.mu.Unlock()
+
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		oc.mu.Lock()
+		e.waiters--
+		select {
+		case <-e.done:
+		default:
+			if e.waiters == 0 {
+				e.cancel()
+				if oc.entries[key] == e {
+					delete(oc.entries, key)
+				}
+			}
+		}
+		oc.mu.Unlock()
+		return nil, ctx.Err()
+	}
+
+	oc.mu.Lock()
+	e.waiters--
+	oc.mu.Unlock()
+	if e.err != nil {
+		return nil, e.err
+	}
+	return copyObject(e.value), nil
+}
+
+func (oc *objectCache) fetch(ctx context.Context, key string, e *objectCacheEntry, fetch func(ctx context.Context) (interface{}, error)) {
+	defer e.cancel()
+	value, err := fetch(ctx)
+
+	oc.mu.Lock()
+	e.value, e.err = value, err
+	if err != nil {
+		// Don't cache failures, the next event should try again
+		if oc.entries[key] == e {
+			delete(oc.entries, key)
+		}
+	} else {
+		e.expires = oc.now().Add(oc.ttl)
+	}
+	close(e.done)
+	oc.mu.Unlock()
+}
+
+// copyObject returns a deep copy of a cached value, so callers can't
+// change each other's copy.
+func copyObject(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(v)).Interface()
+}
+
+func deepCopy(src reflect.Value) reflect.Value {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			return src
+		}
+		dst := reflect.New(src.Type().Elem())
+		dst.Elem().Set(deepCopy(src.Elem()))
+		return dst
+	case reflect.Interface:
+		if src.IsNil() {
+			return src
+		}
+		dst := reflect.New(src.Type()).Elem()
+		dst.Set(deepCopy(src.Elem()))
+		return dst
+	case reflect.Struct:
+		dst := reflect.New(src.Type()).Elem()
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				dst.Field(i).Set(deepCopy(src.Field(i)))
+			}
+		}
+		return dst
+	case reflect.Slice:
+		if src.IsNil() {
+			return src
+		}
+		dst := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			dst.Index(i).Set(deepCopy(src.Index(i)))
+		}
+		return dst
+	case reflect.Map:
+		if src.IsNil() {
+			return src
+		}
+		dst := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			dst.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return dst
+	default:
+		return src
+	}
+}
@@ -0,0 +1,218 @@
+package onfido
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveWebhookObject_Check(t *testing.T) {
+	var checkFetches int32
+
+	m := mux.NewRouter()
+	m.HandleFunc("/checks/{checkId}", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&checkFetches, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, wErr := w.Write([]byte(`{"id": "check-1", "status": "complete", "report_ids": ["report-1"]}`))
+		assert.NoError(t, wErr)
+	}).Methods("GET")
+	m.HandleFunc("/reports/{reportId}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, wErr := w.Write([]byte(`{"id": "report-1", "name": "document", "status": "complete"}`))
+		assert.NoError(t, wErr)
+	}).Methods("GET")
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	wr := webhookEvent(WebhookResourceCheck, WebhookEventCheckCompleted, "check-1")
+	wr.Payload.Object.Status = "complete"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			obj, err := client.ResolveWebhookObject(context.Background(), wr)
+			if assert.NoError(t, err) {
+				check := obj.(*Check)
+				assert.Equal(t, "check-1", check.ID)
+				if assert.Len(t, check.Reports, 1) {
+					assert.Equal(t, ReportNameDocument, check.Reports[0].Name)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&checkFetches))
+
+	// A status change is fetched again
+	wr.Payload.Object.Status = "reopened"
+	_, err := client.ResolveWebhookObject(context.Background(), wr)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&checkFetches))
+}
+
+func TestResolveWebhookObject_Report(t *testing.T) {
+	m := mux.NewRouter()
+	m.HandleFunc("/reports/{reportId}", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "report-1", mux.Vars(r)["reportId"])
+		w.Header().Set("Content-Type", "application/json")
+		_, wErr := w.Write([]byte(`{"id": "report-1", "name": "document", "status": "complete"}`))
+		assert.NoError(t, wErr)
+	}).Methods("GET")
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	obj, err := client.ResolveWebhookObject(context.Background(), webhookEvent(WebhookResourceReport, WebhookEventReportCompleted, "report-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "report-1", obj.(*Report).ID)
+}
+
+func TestResolveWebhookObject_Document(t *testing.T) {
+	m := mux.NewRouter()
+	m.HandleFunc("/documents/{documentId}", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "doc-1", mux.Vars(r)["documentId"])
+		w.Header().Set("Content-Type", "application/json")
+		_, wErr := w.Write([]byte(`{"id": "doc-1", "type": "passport"}`))
+		assert.NoError(t, wErr)
+	}).Methods("GET")
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	obj, err := client.ResolveWebhookObject(context.Background(), webhookEvent(WebhookResourceDocument, WebhookEventDocumentUploaded, "doc-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "doc-1", obj.(*Document).ID)
+}
+
+func TestResolveWebhookObject_OtherResource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/workflow_runs/run-1", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, wErr := w.Write([]byte(`{"id": "run-1", "status": "approved"}`))
+		assert.NoError(t, wErr)
+	}))
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	wr := webhookEvent(WebhookResourceWorkflowRun, WebhookEventWorkflowRunCompleted, "run-1")
+	wr.Payload.Object.Href = srv.URL + "/workflow_runs/run-1"
+
+	obj, err := client.ResolveWebhookObject(context.Background(), wr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var run struct {
+		Status string `json:"status"`
+	}
+	assert.NoError(t, json.Unmarshal(obj.(json.RawMessage), &run))
+	assert.Equal(t, "approved", run.Status)
+}
+
+func TestResolveWebhookObject_Missing(t *testing.T) {
+	client := NewClient("123").(*client)
+	_, err := client.ResolveWebhookObject(context.Background(), &WebhookRequest{})
+	assert.Equal(t, ErrMissingWebhookObject, err)
+}
+
+func TestResolveWebhookObject_WaiterCancels(t *testing.T) {
+	release := make(chan struct{})
+	requested := make(chan struct{}, 1)
+	m := mux.NewRouter()
+	m.HandleFunc("/reports/{reportId}", func(w http.ResponseWriter, r *http.Request) {
+		requested <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, wErr := w.Write([]byte(`{"id": "report-1", "name": "document", "properties": {"issuing_country": "GBR"}}`))
+		assert.NoError(t, wErr)
+	}).Methods("GET")
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+	wr := webhookEvent(WebhookResourceReport, WebhookEventReportCompleted, "report-1")
+
+	// The first caller gives up, which doesn't fail the second
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := client.ResolveWebhookObject(ctx, wr)
+		firstErr <- err
+	}()
+	<-requested
+
+	second := make(chan interface{}, 1)
+	go func() {
+		obj, err := client.ResolveWebhookObject(context.Background(), wr)
+		assert.NoError(t, err)
+		second <- obj
+	}()
+	for waiters := 0; waiters < 2; {
+		time.Sleep(time.Millisecond)
+		client.objectCache.mu.Lock()
+		for _, e := range client.objectCache.entries {
+			waiters = e.waiters
+		}
+		client.objectCache.mu.Unlock()
+	}
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-firstErr)
+	close(release)
+
+	report := (<-second).(*Report)
+	assert.Equal(t, "report-1", report.ID)
+
+	// Cached results are copies
+	report.Properties["issuing_country"] = "FRA"
+	obj, err := client.ResolveWebhookObject(context.Background(), wr)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "GBR", obj.(*Report).Properties["issuing_country"])
+	}
+}
+
+func TestResolveWebhookObject_AllWaitersCancel(t *testing.T) {
+	requestCancelled := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(requestCancelled)
+	}))
+	defer srv.Close()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.ResolveWebhookObject(ctx, webhookEvent(WebhookResourceReport, WebhookEventReportCompleted, "report-1"))
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	select {
+	case <-requestCancelled:
+	case <-time.After(time.Second):
+		t.Fatal("fetch wasn't cancelled")
+	}
+}
@@ -0,0 +1,242 @@
+package onfido
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WebhookSyncOp represents an operation planned by SyncWebhooks
+type WebhookSyncOp string
+
+// Supported sync operations
+const (
+	WebhookSyncCreate WebhookSyncOp = "create"
+	WebhookSyncUpdate WebhookSyncOp = "update"
+	WebhookSyncDelete WebhookSyncOp = "delete"
+	WebhookSyncNoop   WebhookSyncOp = "noop"
+	// WebhookSyncSkip is planned for undesired webhooks outside OwnedURLPrefix
+	WebhookSyncSkip WebhookSyncOp = "skip"
+)
+
+// ErrWebhookNotOwned is returned when a desired webhook's URL is outside OwnedURLPrefix
+var ErrWebhookNotOwned = errors.New("webhook url is not owned")
+
+// SyncWebhooksOptions configures SyncWebhooks.
+type SyncWebhooksOptions struct {
+	// DryRun plans the changes without applying them.
+	DryRun bool
+	// OwnedURLPrefix limits deletions to webhooks whose URL starts with it.
+	// Webhooks outside the prefix are left untouched, and if it is empty
+	// no webhooks are deleted.
+	OwnedURLPrefix string
+}
+
+// WebhookSyncAction represents a single change planned by SyncWebhooks.
+type WebhookSyncAction struct {
+	Op       WebhookSyncOp
+	URL      string
+	Existing *WebhookRef
+	Desired  *WebhookRefRequest
+	// Result is the webhook returned by Onfido once a create or update is applied.
+	Result *WebhookRef
+}
+
+// WebhookSyncPlan represents the changes needed to converge the registered webhooks.
+type WebhookSyncPlan struct {
+	Actions []WebhookSyncAction
+	Applied bool
+}
+
+// Changes returns the number of actions which create, update or delete a webhook.
+func (p *WebhookSyncPlan) Changes() int {
+	n := 0
+	for _, a := range p.Actions {
+		switch a.Op {
+		case WebhookSyncCreate, WebhookSyncUpdate, WebhookSyncDelete:
+			n++
+		}
+	}
+	return n
+}
+
+// String returns the plan as human readable text, one action per line.
+func (p *WebhookSyncPlan) String() string {
+	var sb strings.Builder
+	for _, a := range p.Actions {
+		id := ""
+		if a.Existing != nil {
+			id = " (" + a.Existing.ID + ")"
+		}
+		fmt.Fprintf(&sb, "%-6s %s%s", a.Op, a.URL, id)
+		if a.Desired != nil && a.Op != WebhookSyncNoop {
+			fmt.Fprintf(&sb, " enabled=%t environments=%v events=%v", a.Desired.Enabled, a.Desired.Environments, a.Desired.Events)
+		}
+		sb.WriteByte('\n')
+	}
+	fmt.Fprintf(&sb, "%d to change", p.Changes())
+	return sb.String()
+}
+
+// SyncWebhooks converges the registered webhooks to the desired set, matched
+// by URL: missing webhooks are created, differing ones updated, and ones no
+// longer desired deleted if they are owned (see SyncWebhooksOptions).
+func (c *client) SyncWebhooks(ctx context.Context, desired []WebhookRefRequest, opts SyncWebhooksOptions) (*WebhookSyncPlan, error) {
+	var existing []*WebhookRef
+	it := c.ListWebhooks()
+	for it.Next(ctx) {
+		existing = append(existing, it.WebhookRef())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	plan, err := planWebhookSync(existing, desired, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return plan, nil
+	}
+
+	for i := range plan.Actions {
+		a := &plan.Actions[i]
+		switch a.Op {
+		case WebhookSyncCreate:
+			a.Result, err = c.CreateWebhook(ctx, *a.Desired)
+		case WebhookSyncUpdate:
+			a.Result, err = c.UpdateWebhook(ctx, a.Existing.ID, *a.Desired)
+		case WebhookSyncDelete:
+			err = c.DeleteWebhook(ctx, a.Existing.ID)
+		}
+		if err != nil {
+			return plan, fmt.Errorf("failed to %s webhook %s: %w", a.Op, a.URL, err)
+		}
+	}
+	plan.Applied = true
+	return plan, nil
+}
+
+func planWebhookSync(existing []*WebhookRef, desired []WebhookRefRequest, opts SyncWebhooksOptions) (*WebhookSyncPlan, error) {
+	owned := func(url string) bool {
+		return opts.OwnedURLPrefix != "" && strings.HasPrefix(url, opts.OwnedURLPrefix)
+	}
+
+	byURL := make(map[string][]*WebhookRef)
+	for _, wr := range existing {
+		byURL[wr.URL] = append(byURL[wr.URL], wr)
+	}
+
+	plan := &WebhookSyncPlan{}
+	seen := make(map[string]bool)
+	for i := range desired {
+		d := &desired[i]
+		if opts.OwnedURLPrefix != "" && !owned(d.URL) {
+			return nil, fmt.Errorf("%w: %s", ErrWebhookNotOwned, d.URL)
+		}
+		if seen[d.URL] {
+			return nil, fmt.Errorf("duplicate desired webhook url: %s", d.URL)
+		}
+		seen[d.URL] = true
+
+		matches := byURL[d.URL]
+		if len(matches) == 0 {
+			plan.Actions = append(plan.Actions, WebhookSyncAction{Op: WebhookSyncCreate, URL: d.URL, Desired: d})
+			continue
+		}
+		op := WebhookSyncNoop
+		if !webhookMatches(matches[0], d) {
+			op = WebhookSyncUpdate
+		}
+		plan.Actions = append(plan.Actions, WebhookSyncAction{Op: op, URL: d.URL, Existing: matches[0], Desired: d})
+		// Any further webhooks registered for the same url are duplicates
+		for _, dup := range matches[1:] {
+			plan.Actions = append(plan.Actions, deleteOrSkip(dup, owned(dup.URL)))
+		}
+	}
+
+	for _, wr := range existing {
+		if !seen[wr.URL] {
+			plan.Actions = append(plan.Actions, deleteOrSkip(wr, owned(wr.URL)))
+		}
+	}
+
+	return plan, nil
+}
+
+func deleteOrSkip(wr *WebhookRef, owned bool) WebhookSyncAction {
+	op := WebhookSyncSkip
+	if owned {
+		op = WebhookSyncDelete
+	}
+	return WebhookSyncAction{Op: op, URL: wr.URL, Existing: wr}
+}
+
+// webhookMatches reports whether the registered webhook has the desired configuration.
+func webhookMatches(wr *WebhookRef, d *WebhookRefRequest) bool {
+	if wr.Enabled != d.Enabled {
+		return false
+	}
+	// Omitted environments and events default to all on Onfido's side, so
+	// compare the effective sets.
+	if !sameStrings(environmentStrings(effectiveEnvironments(wr.Environments)), environmentStrings(effectiveEnvironments(d.Environments))) {
+		return false
+	}
+	if !sameStrings(eventStrings(effectiveEvents(wr.Events)), eventStrings(effectiveEvents(d.Events))) {
+		return false
+	}
+	return true
+}
+
+// effectiveEnvironments returns the environments a webhook receives events
+// for, all of them if none are given.
+func effectiveEnvironments(envs []WebhookEnvironment) []WebhookEnvironment {
+	if len(envs) == 0 {
+		return []WebhookEnvironment{WebhookEnvironmentSandbox, WebhookEnvironmentLive}
+	}
+	return envs
+}
+
+// effectiveEvents returns the events a webhook receives, all of them if
+// none are given.
+func effectiveEvents(events []WebhookEvent) []WebhookEvent {
+	if len(events) == 0 {
+		return WebhookEvents
+	}
+	return events
+}
+
+func environmentStrings(envs []WebhookEnvironment) []string {
+	s := make([]string, len(envs))
+	for i, e := range envs {
+		s[i] = string(e)
+	}
+	return s
+}
+
+func eventStrings(events []WebhookEvent) []string {
+	s := make([]string, len(events))
+	for i, e := range events {
+		s[i] = string(e)
+	}
+	return s
+}
+
+// sameStrings reports whether a and b hold the same set of strings.
+func sameStrings(a, b []string) bool {
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package onfido
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// existingWebhooks returns the webhooks registered before syncing.
+func existingWebhooks() []*WebhookRef {
+	return []*WebhookRef{
+		{ID: "a", URL: "https://example.com/onfido/a", Enabled: true, Events: []WebhookEvent{WebhookEventCheckCompleted}},
+		{ID: "b", URL: "https://example.com/onfido/b", Enabled: true},
+		{ID: "c", URL: "https://example.com/onfido/c", Enabled: true},
+		{ID: "d", URL: "https://other.example.com/hook", Enabled: true},
+	}
+}
+
+var desiredWebhooks = []WebhookRefRequest{
+	{URL: "https://example.com/onfido/a", Enabled: true, Events: []WebhookEvent{WebhookEventCheckCompleted}},
+	{URL: "https://example.com/onfido/b", Enabled: false},
+	{URL: "https://example.com/onfido/e", Enabled: true},
+}
+
+func TestSyncWebhooks_Apply(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+	srv.webhooks = existingWebhooks()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	plan, err := client.SyncWebhooks(context.Background(), desiredWebhooks, SyncWebhooksOptions{
+		OwnedURLPrefix: "https://example.com/onfido/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, plan.Applied)
+	assert.Equal(t, 3, plan.Changes())
+	assert.Equal(t, []string{
+		"update b",
+		"create https://example.com/onfido/e",
+		"delete c",
+	}, srv.webhookCalls())
+
+	var ops []WebhookSyncOp
+	for _, a := range plan.Actions {
+		ops = append(ops, a.Op)
+	}
+	assert.Equal(t, []WebhookSyncOp{WebhookSyncNoop, WebhookSyncUpdate, WebhookSyncCreate, WebhookSyncDelete, WebhookSyncSkip}, ops)
+	assert.Equal(t, "secret", plan.Actions[2].Result.Token)
+}
+
+func TestSyncWebhooks_DryRun(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+	srv.webhooks = existingWebhooks()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	plan, err := client.SyncWebhooks(context.Background(), desiredWebhooks, SyncWebhooksOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.False(t, plan.Applied)
+	assert.Empty(t, srv.webhookCalls())
+	// Without an owned prefix nothing is deleted
+	assert.Equal(t, 2, plan.Changes())
+	assert.Equal(t, `noop   https://example.com/onfido/a (a)
+update https://example.com/onfido/b (b) enabled=false environments=[] events=[]
+create https://example.com/onfido/e enabled=true environments=[] events=[]
+skip   https://example.com/onfido/c (c)
+skip   https://other.example.com/hook (d)
+2 to change`, plan.String())
+}
+
+func TestSyncWebhooks_NotOwned(t *testing.T) {
+	srv := newFakeOnfido(t)
+	defer srv.Close()
+	srv.webhooks = existingWebhooks()
+
+	client := NewClient("123").(*client)
+	client.endpoint = srv.URL
+
+	_, err := client.SyncWebhooks(context.Background(), desiredWebhooks, SyncWebhooksOptions{
+		OwnedURLPrefix: "https://example.com/other/",
+	})
+	assert.True(t, errors.Is(err, ErrWebhookNotOwned))
+	assert.Empty(t, srv.webhookCalls())
+}
+
+func TestWebhookMatches_EffectiveSets(t *testing.T) {
+	all := &WebhookRef{Enabled: true}
+	restricted := &WebhookRef{
+		Enabled:      true,
+		Environments: []WebhookEnvironment{WebhookEnvironmentLive},
+		Events:       []WebhookEvent{WebhookEventCheckCompleted},
+	}
+
+	// Empty desired sets mean all, so a restricted webhook needs updating
+	assert.False(t, webhookMatches(restricted, &WebhookRefRequest{Enabled: true}))
+	assert.False(t, webhookMatches(restricted, &WebhookRefRequest{Enabled: true, Events: []WebhookEvent{WebhookEventCheckCompleted}}))
+	assert.True(t, webhookMatches(restricted, &WebhookRefRequest{
+		Enabled:      true,
+		Environments: []WebhookEnvironment{WebhookEnvironmentLive},
+		Events:       []WebhookEvent{WebhookEventCheckCompleted},
+	}))
+
+	// Every event listed explicitly is the same as none
+	assert.True(t, webhookMatches(all, &WebhookRefRequest{Enabled: true}))
+	assert.True(t, webhookMatches(all, &WebhookRefRequest{
+		Enabled:      true,
+		Environments: []WebhookEnvironment{WebhookEnvironmentSandbox, WebhookEnvironmentLive},
+		Events:       WebhookEvents,
+	}))
+	assert.True(t, webhookMatches(&WebhookRef{Enabled: true, Events: WebhookEvents}, &WebhookRefRequest{Enabled: true}))
+	assert.False(t, webhookMatches(all, &WebhookRefRequest{Enabled: true, Events: []WebhookEvent{WebhookEventCheckCompleted}}))
+}
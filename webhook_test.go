@@ -2,10 +2,19 @@ package onfido
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"os"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNewWebhookFromEnv_MissingToken(t *testing.T) {
@@ -38,9 +47,10 @@ func TestValidateSignature_InvalidSignature(t *testing.T) {
 	if err == nil {
 		t.Fatal()
 	}
-	if err != ErrInvalidWebhookSignature {
+	if !errors.Is(err, ErrInvalidWebhookSignature) {
 		t.Fatal("expected error to match ErrInvalidWebhookSignature")
 	}
+	assert.Contains(t, err.Error(), WebhookSignatureHeader)
 }
 
 func TestValidateSignature_ValidSignature(t *testing.T) {
@@ -63,9 +73,10 @@ func TestParseFromRequest_InvalidSignature(t *testing.T) {
 	if err == nil {
 		t.Fatal()
 	}
-	if err != ErrInvalidWebhookSignature {
+	if !errors.Is(err, ErrInvalidWebhookSignature) {
 		t.Fatal("expected error to match ErrInvalidWebhookSignature")
 	}
+	assert.Equal(t, &WebhookSignatureError{Header: WebhookSignatureHeader}, err)
 }
 
 func TestParseFromRequest_SkipSignatureValidation(t *testing.T) {
@@ -108,3 +119,226 @@ func TestParseFromRequest_ValidSignature(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func signWebhookBody(t *testing.T, token string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	if _, err := mac.Write(body); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseFromRequest_TypedPayload(t *testing.T) {
+	body := []byte(`{"payload": {
+		"resource_type": "check",
+		"action": "check.completed",
+		"object": {
+			"id": "cd3c0fae-6e11-4ef3-9f81-a9a4d8b4c9a5",
+			"status": "complete",
+			"completed_at_iso8601": "2020-05-12T10:37:04Z",
+			"href": "https://api.onfido.com/v3/checks/cd3c0fae-6e11-4ef3-9f81-a9a4d8b4c9a5",
+			"new_field": "kept"
+		}
+	}}`)
+	req := &http.Request{
+		Header: make(map[string][]string),
+		Body:   ioutil.NopCloser(bytes.NewBuffer(body)),
+	}
+	req.Header.Add(WebhookSignatureHeader, signWebhookBody(t, "abc123", body))
+
+	wh := webhook{Token: "abc123"}
+	wr, err := wh.ParseFromRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, WebhookResourceCheck, wr.Payload.ResourceType)
+	assert.Equal(t, WebhookEventCheckCompleted, wr.Payload.Action)
+	assert.True(t, wr.Payload.Action.IsKnown())
+	assert.Equal(t, WebhookResourceCheck, wr.Payload.Action.ResourceType())
+	assert.Equal(t, time.Date(2020, 5, 12, 10, 37, 4, 0, time.UTC), wr.Payload.Object.CompletedAtTime)
+	assert.Equal(t, body, []byte(wr.Raw))
+
+	var extra struct {
+		NewField string `json:"new_field"`
+	}
+	if err := json.Unmarshal(wr.Payload.Object.Raw, &extra); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "kept", extra.NewField)
+}
+
+func TestWebhookEvent_ResourceType(t *testing.T) {
+	assert.Equal(t, WebhookResourceWatchlistMonitor, WebhookEventWatchlistMonitorMatchesUpdated.ResourceType())
+	assert.Equal(t, WebhookResourceWorkflowRun, WebhookEventWorkflowRunCompleted.ResourceType())
+	assert.True(t, WebhookEvent("document.uploaded").IsKnown())
+	assert.Equal(t, WebhookResourceDocument, WebhookEventDocumentUploaded.ResourceType())
+	assert.False(t, WebhookEvent("document.deleted").IsKnown())
+}
+
+func TestNewWebhookFromEnv_MultipleTokens(t *testing.T) {
+	os.Setenv(WebhookTokensEnv, "new-token, old-token,")
+	defer os.Setenv(WebhookTokensEnv, "")
+
+	wh, err := NewWebhookFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StaticTokens{"new-token", "old-token"}, wh.(*webhook).TokenSource)
+}
+
+func TestMatchSignature_RotatedTokens(t *testing.T) {
+	body := []byte("hello world")
+	wh := NewWebhook("xyz789", "abc123")
+
+	i, err := wh.MatchSignature(body, signWebhookBody(t, "abc123", body))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, i)
+
+	i, err = wh.MatchSignature(body, signWebhookBody(t, "xyz789", body))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, i)
+
+	_, err = wh.MatchSignature(body, signWebhookBody(t, "retired", body))
+	assert.Equal(t, &WebhookSignatureError{Header: WebhookSignatureHeader}, err)
+}
+
+func TestMatchSignature_NoTokens(t *testing.T) {
+	body := []byte("hello world")
+	emptyKeySig := signWebhookBody(t, "", body)
+
+	for _, wh := range []Webhook{NewWebhook(), NewWebhook(""), NewWebhookWithTokenSource(StaticTokens{})} {
+		_, err := wh.MatchSignature(body, emptyKeySig)
+		assert.Equal(t, ErrNoWebhookTokens, err)
+	}
+
+	// An empty token alongside others is skipped
+	i, err := NewWebhook("", "abc123").MatchSignature(body, signWebhookBody(t, "abc123", body))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, i)
+	_, err = NewWebhook("", "abc123").MatchSignature(body, emptyKeySig)
+	assert.Equal(t, &WebhookSignatureError{Header: WebhookSignatureHeader}, err)
+}
+
+func TestWebhookHandler_NoTokens(t *testing.T) {
+	h := NewWebhookHandler(NewWebhook(), WebhookHandlerOptions{ErrorLog: log.New(ioutil.Discard, "", 0)})
+	body := `{"payload": {"action": "check.completed"}}`
+	assert.Equal(t, http.StatusInternalServerError, serveWebhook(t, h, body, signWebhookBody(t, "", []byte(body))).Code)
+}
+
+func TestParseFromRequest_TokenIndex(t *testing.T) {
+	body := []byte(`{"payload": {"action": "check.completed"}}`)
+	req := &http.Request{
+		Header: make(map[string][]string),
+		Body:   ioutil.NopCloser(bytes.NewBuffer(body)),
+	}
+	req.Header.Add(WebhookSignatureHeader, signWebhookBody(t, "old", body))
+
+	wh := NewWebhookWithTokenSource(StaticTokens{"new", "old"})
+	wr, err := wh.ParseFromRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, wr.TokenIndex)
+}
+
+func TestParseFromRequest_SignatureSchemes(t *testing.T) {
+	const (
+		body   = `{"msg": "hello world"}`
+		sha2   = "b469eabb36776543320fc09ed03451c34706daa3a730a561868ab2cc4399f8ec"
+		sha1   = "d2ef30601350308c1f1c25c5fbf359badb95cbfb"
+		bad256 = "8c301acf7e955038b486de8f2a35f7f28bb5755fd1f77e1dbf9ef9e27713ad0d"
+		bad1   = "fcc98c5b4f306cfe6b5b8fcce03ddb33fc13ae6b"
+	)
+
+	tests := []struct {
+		name    string
+		scheme  WebhookSignatureScheme
+		headers map[string]string
+		err     error
+		header  string
+	}{
+		{"sha256", WebhookSchemeSHA256, map[string]string{WebhookSignatureHeader: sha2}, nil, ""},
+		{"sha256 mismatch", WebhookSchemeSHA256, map[string]string{WebhookSignatureHeader: bad256}, ErrInvalidWebhookSignature, WebhookSignatureHeader},
+		{"sha256 ignores sha1", WebhookSchemeSHA256, map[string]string{WebhookLegacySignatureHeader: sha1}, ErrMissingWebhookSignature, WebhookSignatureHeader},
+		{"sha1", WebhookSchemeSHA1, map[string]string{WebhookLegacySignatureHeader: sha1}, nil, ""},
+		{"sha1 mismatch", WebhookSchemeSHA1, map[string]string{WebhookLegacySignatureHeader: bad1}, ErrInvalidWebhookSignature, WebhookLegacySignatureHeader},
+		{"sha1 missing", WebhookSchemeSHA1, map[string]string{WebhookSignatureHeader: sha2}, ErrMissingWebhookSignature, WebhookLegacySignatureHeader},
+		{"either prefers sha256", WebhookSchemeSHA256OrSHA1, map[string]string{WebhookSignatureHeader: bad256, WebhookLegacySignatureHeader: sha1}, ErrInvalidWebhookSignature, WebhookSignatureHeader},
+		{"either falls back to sha1", WebhookSchemeSHA256OrSHA1, map[string]string{WebhookLegacySignatureHeader: sha1}, nil, ""},
+		{"either missing", WebhookSchemeSHA256OrSHA1, nil, ErrMissingWebhookSignature, WebhookSignatureHeader + " or " + WebhookLegacySignatureHeader},
+		{"both", WebhookSchemeSHA256AndSHA1, map[string]string{WebhookSignatureHeader: sha2, WebhookLegacySignatureHeader: sha1}, nil, ""},
+		{"both missing sha1", WebhookSchemeSHA256AndSHA1, map[string]string{WebhookSignatureHeader: sha2}, ErrMissingWebhookSignature, WebhookLegacySignatureHeader},
+		{"both sha1 mismatch", WebhookSchemeSHA256AndSHA1, map[string]string{WebhookSignatureHeader: sha2, WebhookLegacySignatureHeader: bad1}, ErrInvalidWebhookSignature, WebhookLegacySignatureHeader},
+		{"both sha256 mismatch", WebhookSchemeSHA256AndSHA1, map[string]string{WebhookSignatureHeader: bad256, WebhookLegacySignatureHeader: sha1}, ErrInvalidWebhookSignature, WebhookSignatureHeader},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			req := &http.Request{
+				Header: make(map[string][]string),
+				Body:   ioutil.NopCloser(bytes.NewBufferString(body)),
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			_, err := NewWebhookWithScheme(tt.scheme, "abc123").ParseFromRequest(req)
+			if tt.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tt.err), "expected %v, got %v", tt.err, err)
+
+			var sigErr *WebhookSignatureError
+			if tt.header == "" {
+				assert.False(t, errors.As(err, &sigErr))
+			} else if assert.True(t, errors.As(err, &sigErr)) {
+				assert.Equal(t, tt.header, sigErr.Header)
+				assert.Contains(t, err.Error(), tt.header)
+			}
+		})
+	}
+}
+
+func TestMatchSignature_Schemes(t *testing.T) {
+	const (
+		body = `{"msg": "hello world"}`
+		sha2 = "b469eabb36776543320fc09ed03451c34706daa3a730a561868ab2cc4399f8ec"
+		sha1 = "d2ef30601350308c1f1c25c5fbf359badb95cbfb"
+	)
+
+	tests := []struct {
+		name      string
+		scheme    WebhookSignatureScheme
+		signature string
+		err       error
+		header    string
+	}{
+		{"sha256", WebhookSchemeSHA256, sha2, nil, ""},
+		{"sha256 rejects sha1", WebhookSchemeSHA256, sha1, ErrInvalidWebhookSignature, WebhookSignatureHeader},
+		{"sha256 missing", WebhookSchemeSHA256, "", ErrMissingWebhookSignature, WebhookSignatureHeader},
+		{"sha1", WebhookSchemeSHA1, sha1, nil, ""},
+		{"sha1 rejects sha256", WebhookSchemeSHA1, sha2, ErrInvalidWebhookSignature, WebhookLegacySignatureHeader},
+		{"either sha256", WebhookSchemeSHA256OrSHA1, sha2, nil, ""},
+		{"either sha1", WebhookSchemeSHA256OrSHA1, sha1, nil, ""},
+		{"both needs both headers", WebhookSchemeSHA256AndSHA1, sha2, ErrMissingWebhookSignature, WebhookLegacySignatureHeader},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewWebhookWithScheme(tt.scheme, "abc123").ValidateSignature([]byte(body), tt.signature)
+			if tt.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tt.err), "expected %v, got %v", tt.err, err)
+			var sigErr *WebhookSignatureError
+			if assert.True(t, errors.As(err, &sigErr)) {
+				assert.Equal(t, tt.header, sigErr.Header)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strings"
 )
 
 // WebhookEnvironment represents an environment type (see `WebhookEnvironment*` constants for possible values)
@@ -30,8 +31,57 @@ const (
 	WebhookEventCheckCompleted         WebhookEvent = "check.completed"
 	WebhookEventCheckFormOpened        WebhookEvent = "check.form_opened"
 	WebhookEventCheckFormCompleted     WebhookEvent = "check.form_completed"
+
+	WebhookEventDocumentUploaded WebhookEvent = "document.uploaded"
+
+	WebhookEventWorkflowRunCompleted           WebhookEvent = "workflow_run.completed"
+	WebhookEventWorkflowTaskStarted            WebhookEvent = "workflow_task.started"
+	WebhookEventWorkflowTaskCompleted          WebhookEvent = "workflow_task.completed"
+	WebhookEventWatchlistMonitorMatchesUpdated WebhookEvent = "watchlist_monitor.matches_updated"
+	WebhookEventAuditLogCreated                WebhookEvent = "audit_log.created"
 )
 
+// WebhookEvents lists all known webhook events
+var WebhookEvents = []WebhookEvent{
+	WebhookEventReportWithdrawn,
+	WebhookEventReportResumed,
+	WebhookEventReportCancelled,
+	WebhookEventReportAwaitingApproval,
+	WebhookEventReportInitiated,
+	WebhookEventReportCompleted,
+	WebhookEventCheckStarted,
+	WebhookEventCheckReopened,
+	WebhookEventCheckWithdrawn,
+	WebhookEventCheckCompleted,
+	WebhookEventCheckFormOpened,
+	WebhookEventCheckFormCompleted,
+	WebhookEventDocumentUploaded,
+	WebhookEventWorkflowRunCompleted,
+	WebhookEventWorkflowTaskStarted,
+	WebhookEventWorkflowTaskCompleted,
+	WebhookEventWatchlistMonitorMatchesUpdated,
+	WebhookEventAuditLogCreated,
+}
+
+// IsKnown reports whether the event is one of the WebhookEvent* constants.
+func (e WebhookEvent) IsKnown() bool {
+	for _, k := range WebhookEvents {
+		if e == k {
+			return true
+		}
+	}
+	return false
+}
+
+// ResourceType returns the type of resource the event refers to, e.g. check
+// for check.completed.
+func (e WebhookEvent) ResourceType() WebhookResourceType {
+	if i := strings.LastIndex(string(e), "."); i >= 0 {
+		return WebhookResourceType(e[:i])
+	}
+	return ""
+}
+
 // WebhookRefRequest represents a webhook request to Onfido API
 type WebhookRefRequest struct {
 	URL          string               `json:"url"` // Onfido requires that this must be HTTPS
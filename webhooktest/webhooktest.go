@@ -0,0 +1,208 @@
+// Package webhooktest builds and delivers signed Onfido webhook requests,
+// for exercising webhook endpoints without the Onfido sandbox.
+package webhooktest
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/esqimo/go-onfido"
+)
+
+// fixture describes the object Onfido sends in an event's payload.
+type fixture struct {
+	// status is the object's status, empty for objects without one
+	status string
+	// collection is the API path the object's href is in, empty if the
+	// object can't be retrieved on its own
+	collection string
+}
+
+// fixtures maps every event to its object. Workflow tasks are retrieved
+// through their workflow run, see NewWorkflowTaskEvent.
+var fixtures = map[onfido.WebhookEvent]fixture{
+	onfido.WebhookEventReportWithdrawn:                {string(onfido.ReportStatusWithdrawn), "reports"},
+	onfido.WebhookEventReportResumed:                  {string(onfido.ReportStatusAwaitingData), "reports"},
+	onfido.WebhookEventReportCancelled:                {string(onfido.ReportStatusCancelled), "reports"},
+	onfido.WebhookEventReportAwaitingApproval:         {string(onfido.ReportStatusAwaitingApproval), "reports"},
+	onfido.WebhookEventReportInitiated:                {string(onfido.ReportStatusAwaitingData), "reports"},
+	onfido.WebhookEventReportCompleted:                {string(onfido.ReportStatusComplete), "reports"},
+	onfido.WebhookEventCheckStarted:                   {string(onfido.CheckStatusInProgress), "checks"},
+	onfido.WebhookEventCheckReopened:                  {string(onfido.CheckStatusReopened), "checks"},
+	onfido.WebhookEventCheckWithdrawn:                 {string(onfido.CheckStatusWithdrawn), "checks"},
+	onfido.WebhookEventCheckCompleted:                 {string(onfido.CheckStatusComplete), "checks"},
+	onfido.WebhookEventCheckFormOpened:                {string(onfido.CheckStatusAwaitingApplicant), "checks"},
+	onfido.WebhookEventCheckFormCompleted:             {string(onfido.CheckStatusInProgress), "checks"},
+	onfido.WebhookEventDocumentUploaded:               {"", "documents"},
+	onfido.WebhookEventWorkflowRunCompleted:           {"approved", "workflow_runs"},
+	onfido.WebhookEventWorkflowTaskStarted:            {"started", ""},
+	onfido.WebhookEventWorkflowTaskCompleted:          {"completed", ""},
+	onfido.WebhookEventWatchlistMonitorMatchesUpdated: {"", "watchlist_monitors"},
+	onfido.WebhookEventAuditLogCreated:                {"", ""},
+}
+
+// Event describes a webhook event to deliver.
+type Event struct {
+	Action      onfido.WebhookEvent
+	ObjectID    string
+	Status      string
+	CompletedAt time.Time
+	Href        string
+}
+
+// NewEvent creates an event for the given action and object ID, with the
+// status, completion time and href Onfido would send. Onfido sends the
+// time the event happened as completed_at_iso8601 for every action, so
+// CompletedAt is always set to now. Objects which can't be retrieved on
+// their own, such as audit log entries, have no href; use
+// NewWorkflowTaskEvent for workflow task events.
+func NewEvent(action onfido.WebhookEvent, objectID string) Event {
+	f := fixtures[action]
+	e := Event{
+		Action:      action,
+		ObjectID:    objectID,
+		Status:      f.status,
+		CompletedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if f.collection != "" {
+		e.Href = onfido.DefaultEndpoint + "/" + f.collection + "/" + objectID
+	}
+	return e
+}
+
+// NewWorkflowTaskEvent creates a workflow task event like NewEvent, with
+// the href of the task within its workflow run.
+func NewWorkflowTaskEvent(action onfido.WebhookEvent, workflowRunID, taskID string) Event {
+	e := NewEvent(action, taskID)
+	e.Href = onfido.DefaultEndpoint + "/workflow_runs/" + workflowRunID + "/tasks/" + taskID
+	return e
+}
+
+// Request returns the webhook request for the event.
+func (e Event) Request() *onfido.WebhookRequest {
+	var wr onfido.WebhookRequest
+	wr.Payload.ResourceType = e.Action.ResourceType()
+	wr.Payload.Action = e.Action
+	wr.Payload.Object.ID = e.ObjectID
+	wr.Payload.Object.Status = e.Status
+	wr.Payload.Object.Href = e.Href
+	if !e.CompletedAt.IsZero() {
+		wr.Payload.Object.CompletedAt = e.CompletedAt.UTC().Format(time.RFC3339)
+	}
+	return &wr
+}
+
+// Body returns the JSON body of the event's webhook request.
+func (e Event) Body() ([]byte, error) {
+	return json.Marshal(e.Request())
+}
+
+// Sign returns the signature Onfido sends for the body, the hex encoded
+// HMAC-SHA256 of the body keyed with the webhook token.
+func Sign(token string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewRequest creates a signed webhook HTTP request for the event.
+func NewRequest(ctx context.Context, url, token string, e Event) (*http.Request, error) {
+	body, err := e.Body()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(onfido.WebhookSignatureHeader, Sign(token, body))
+	return req, nil
+}
+
+// ErrNoTarget is returned when a Sender has neither a URL nor a Handler
+var ErrNoTarget = errors.New("webhooktest: sender has no url or handler")
+
+// Sender delivers signed webhook events to a URL or directly into an http.Handler.
+type Sender struct {
+	// Token is the webhook token used to sign requests
+	Token string
+	// URL receives the requests, unless Handler is set
+	URL string
+	// Handler receives the requests in process
+	Handler http.Handler
+	// Client sends requests to URL, http.DefaultClient if nil
+	Client onfido.HTTPRequester
+
+	// Duplicates is the number of additional times each event is delivered
+	Duplicates int
+	// Delay is waited before each delivery
+	Delay time.Duration
+	// CorruptSignature sends an invalid signature
+	CorruptSignature bool
+}
+
+// Send delivers the event and returns the response status code of each delivery.
+func (s *Sender) Send(ctx context.Context, e Event) ([]int, error) {
+	if s.URL == "" && s.Handler == nil {
+		return nil, ErrNoTarget
+	}
+
+	var codes []int
+	for i := 0; i <= s.Duplicates; i++ {
+		if s.Delay > 0 {
+			select {
+			case <-time.After(s.Delay):
+			case <-ctx.Done():
+				return codes, ctx.Err()
+			}
+		}
+
+		code, err := s.deliver(ctx, e)
+		if err != nil {
+			return codes, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func (s *Sender) deliver(ctx context.Context, e Event) (int, error) {
+	url := s.URL
+	if s.Handler != nil {
+		url = "http://webhooktest.local/"
+	}
+	req, err := NewRequest(ctx, url, s.Token, e)
+	if err != nil {
+		return 0, err
+	}
+	if s.CorruptSignature {
+		req.Header.Set(onfido.WebhookSignatureHeader, Sign(s.Token+"-corrupt", []byte(url)))
+	}
+
+	if s.Handler != nil {
+		rec := httptest.NewRecorder()
+		s.Handler.ServeHTTP(rec, req)
+		return rec.Code, nil
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
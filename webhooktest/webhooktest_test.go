@@ -0,0 +1,109 @@
+package webhooktest
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/esqimo/go-onfido"
+)
+
+func TestSign(t *testing.T) {
+	// Same vector as the onfido package's signature validation tests
+	assert.Equal(t,
+		"8c301acf7e955038b486de8f2a35f7f28bb5755fd1f77e1dbf9ef9e27713ad0d",
+		Sign("abc123", []byte("hello world")))
+}
+
+func TestNewEvent(t *testing.T) {
+	e := NewEvent(onfido.WebhookEventCheckCompleted, "check-1")
+	assert.Equal(t, "complete", e.Status)
+	assert.Equal(t, onfido.DefaultEndpoint+"/checks/check-1", e.Href)
+	assert.False(t, e.CompletedAt.IsZero())
+
+	e = NewEvent(onfido.WebhookEventReportInitiated, "report-1")
+	assert.Equal(t, "awaiting_data", e.Status)
+	assert.False(t, e.CompletedAt.IsZero())
+
+	wr := e.Request()
+	assert.Equal(t, e.CompletedAt.Format(time.RFC3339), wr.Payload.Object.CompletedAt)
+	body, err := e.Body()
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(body), `"completed_at_iso8601":"`+e.CompletedAt.Format(time.RFC3339)+`"`)
+	}
+}
+
+func TestNewEvent_AllEvents(t *testing.T) {
+	for _, action := range onfido.WebhookEvents {
+		_, ok := fixtures[action]
+		assert.True(t, ok, action)
+	}
+
+	e := NewEvent(onfido.WebhookEventDocumentUploaded, "doc-1")
+	assert.Empty(t, e.Status)
+	assert.Equal(t, onfido.DefaultEndpoint+"/documents/doc-1", e.Href)
+
+	e = NewEvent(onfido.WebhookEventWatchlistMonitorMatchesUpdated, "monitor-1")
+	assert.Equal(t, onfido.DefaultEndpoint+"/watchlist_monitors/monitor-1", e.Href)
+
+	e = NewEvent(onfido.WebhookEventAuditLogCreated, "log-1")
+	assert.Empty(t, e.Href)
+
+	e = NewEvent(onfido.WebhookEventWorkflowRunCompleted, "run-1")
+	assert.Equal(t, "approved", e.Status)
+	assert.Equal(t, onfido.DefaultEndpoint+"/workflow_runs/run-1", e.Href)
+
+	e = NewWorkflowTaskEvent(onfido.WebhookEventWorkflowTaskCompleted, "run-1", "profile_data")
+	assert.Equal(t, "profile_data", e.ObjectID)
+	assert.Equal(t, "completed", e.Status)
+	assert.Equal(t, onfido.DefaultEndpoint+"/workflow_runs/run-1/tasks/profile_data", e.Href)
+}
+
+func newHandler(calls *int) http.Handler {
+	h := onfido.NewWebhookHandler(onfido.NewWebhook("abc123"), onfido.WebhookHandlerOptions{
+		DedupStore: onfido.NewMemoryDedupStore(10),
+		ErrorLog:   log.New(ioutil.Discard, "", 0),
+	})
+	h.OnCheckCompleted(func(ctx context.Context, wr *onfido.WebhookRequest) error {
+		*calls++
+		return nil
+	})
+	return h
+}
+
+func TestSender_Handler(t *testing.T) {
+	calls := 0
+	s := &Sender{Token: "abc123", Handler: newHandler(&calls), Duplicates: 2}
+
+	codes, err := s.Send(context.Background(), NewEvent(onfido.WebhookEventCheckCompleted, "check-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int{200, 200, 200}, codes)
+	assert.Equal(t, 1, calls)
+}
+
+func TestSender_URL_CorruptSignature(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(newHandler(&calls))
+	defer srv.Close()
+
+	s := &Sender{Token: "abc123", URL: srv.URL, CorruptSignature: true}
+	codes, err := s.Send(context.Background(), NewEvent(onfido.WebhookEventCheckCompleted, "check-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int{http.StatusBadRequest}, codes)
+	assert.Equal(t, 0, calls)
+}
+
+func TestSender_NoTarget(t *testing.T) {
+	_, err := (&Sender{}).Send(context.Background(), NewEvent(onfido.WebhookEventCheckCompleted, "check-1"))
+	assert.Equal(t, ErrNoTarget, err)
+}